@@ -0,0 +1,86 @@
+package tailf
+
+// acMatcher finds if any of a set of literal patterns occurs in a
+// line, in a single pass, using the Aho-Corasick automaton.
+type acMatcher struct {
+	nodes []acNode
+}
+
+type acNode struct {
+	next map[byte]int32
+	fail int32
+	// final is set when a pattern ends here, or on a node this one
+	// falls back to.
+	final bool
+}
+
+func newACMatcher(patterns []string) *acMatcher {
+	m := &acMatcher{nodes: []acNode{{next: make(map[byte]int32)}}}
+
+	// build the trie
+	for _, p := range patterns {
+		cur := int32(0)
+		for i := 0; i < len(p); i++ {
+			nxt, ok := m.nodes[cur].next[p[i]]
+			if !ok {
+				nxt = int32(len(m.nodes))
+				m.nodes = append(m.nodes, acNode{next: make(map[byte]int32)})
+				m.nodes[cur].next[p[i]] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].final = true
+	}
+
+	// link the fail transitions, breadth first
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) != 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[cur].next {
+			fail := m.nodes[cur].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[c]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if nxt, ok := m.nodes[fail].next[c]; ok && nxt != child {
+				fail = nxt
+			} else {
+				fail = 0
+			}
+			m.nodes[child].fail = fail
+			m.nodes[child].final = m.nodes[child].final || m.nodes[fail].final
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+func (m *acMatcher) match(line []byte) bool {
+	if m.nodes[0].final {
+		// the empty pattern matches everything
+		return true
+	}
+	cur := int32(0)
+	for _, c := range line {
+		for {
+			if nxt, ok := m.nodes[cur].next[c]; ok {
+				cur = nxt
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		if m.nodes[cur].final {
+			return true
+		}
+	}
+	return false
+}
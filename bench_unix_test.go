@@ -0,0 +1,122 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package tailf_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// cpuTime returns the CPU time the process used so far.
+func cpuTime(b *testing.B) time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		b.Fatal(err)
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+// CPU used by a follower with nothing to read, its reader blocked in Read
+func BenchmarkIdle(b *testing.B) {
+	filename := path.Join(benchDir(b), "app.log")
+	if err := ioutil.WriteFile(filename, nil, 0600); err != nil {
+		b.Fatal(err)
+	}
+	follow, err := tailf.FollowWithOptions(filename, tailf.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer follow.Close()
+	go func() {
+		buf := make([]byte, 100)
+		for {
+			if _, err := follow.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	const idle = 100 * time.Millisecond
+	b.ResetTimer()
+	start := cpuTime(b)
+	for i := 0; i < b.N; i++ {
+		time.Sleep(idle)
+	}
+	used := cpuTime(b) - start
+	b.StopTimer()
+	b.ReportMetric(float64(used)/float64(time.Duration(b.N)*idle)*100, "%cpu")
+}
+
+// maxFollowers returns how many followers the process can have at most,
+// each using an inotify instance and two file descriptors, leaving some
+// for the rest.
+func maxFollowers(want int) int {
+	if data, err := ioutil.ReadFile("/proc/sys/fs/inotify/max_user_instances"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && n-8 < want {
+			want = n - 8
+		}
+	}
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err == nil {
+		if n := int(limit.Cur-32) / 2; n < want {
+			want = n
+		}
+	}
+	return want
+}
+
+// Memory used by each of many followers. The inotify instances and the
+// open files of the process limit how many can follow at once, which
+// fs.inotify.max_user_instances and ulimit -n raise.
+func BenchmarkFollowers(b *testing.B) {
+	n := maxFollowers(1000)
+	if n < 1000 {
+		b.Logf("limited to %d followers by the inotify instances or the open files", n)
+	}
+	dir := benchDir(b)
+	filenames := make([]string, n)
+	for i := range filenames {
+		filenames[i] = path.Join(dir, fmt.Sprintf("app%d.log", i))
+		if err := ioutil.WriteFile(filenames[i], []byte("hello\n"), 0600); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var used int64
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		before := memInUse()
+		followers := make([]*tailf.Follower, n)
+		for j, filename := range filenames {
+			f, err := tailf.FollowWithOptions(filename, tailf.Options{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			followers[j] = f
+		}
+		used += int64(memInUse()) - int64(before)
+		for _, f := range followers {
+			f.Close()
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(used)/float64(b.N*n), "B/follower")
+}
+
+// memInUse returns the heap and the stacks in use, once collected.
+func memInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc + stats.StackInuse
+}
@@ -0,0 +1,229 @@
+package tailf
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrSlowSubscriber is returned to a subscriber that was disconnected
+// from a Broadcaster because it didn't keep up.
+var ErrSlowSubscriber = errors.New("tailf: subscriber disconnected for being too slow")
+
+// SlowPolicy decides what a Broadcaster does with a subscriber whose
+// buffer is full.
+type SlowPolicy int
+
+const (
+	// Block waits for the subscriber to make room, which holds
+	// back every other subscriber in the meantime.
+	Block SlowPolicy = iota
+	// DropOldest discards the oldest buffered line of the
+	// subscriber to make room for the new one.
+	DropOldest
+	// Disconnect cuts off the subscriber, which will read
+	// ErrSlowSubscriber once it has drained its buffer.
+	Disconnect
+)
+
+// Broadcaster reads the lines of a single follower and hands each of
+// them to all its subscribers. This spares a watch and a file handle
+// per consumer of the same file.
+type Broadcaster struct {
+	src   io.ReadCloser
+	lines *LineReader
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	err  error
+}
+
+// NewBroadcaster starts broadcasting the lines read from src, usually
+// a Follower. Closing the Broadcaster closes src.
+func NewBroadcaster(src io.ReadCloser) *Broadcaster {
+	b := &Broadcaster{
+		src:   src,
+		lines: NewLineReader(src),
+		subs:  make(map[*Subscription]struct{}),
+	}
+	go b.pump()
+	return b
+}
+
+// Subscribe returns a new subscriber, which will receive the lines read
+// from now on. It buffers up to size lines, after which policy applies.
+func (b *Broadcaster) Subscribe(size int, policy SlowPolicy) *Subscription {
+	if size < 1 {
+		size = 1
+	}
+	s := &Subscription{b: b, size: size, policy: policy}
+	s.cond = sync.NewCond(&s.mu)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		s.err = b.err
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Close stops following the file. Subscribers will read what they have
+// buffered and then reach io.EOF.
+func (b *Broadcaster) Close() error {
+	return b.src.Close()
+}
+
+func (b *Broadcaster) pump() {
+	for {
+		rec, err := b.lines.Next()
+		if err != nil {
+			b.mu.Lock()
+			b.err = err
+			subs := b.subscribers()
+			b.subs = nil
+			b.mu.Unlock()
+			for _, s := range subs {
+				s.end(err)
+			}
+			return
+		}
+
+		b.mu.Lock()
+		subs := b.subscribers()
+		b.mu.Unlock()
+		for _, s := range subs {
+			if !s.push(rec) {
+				b.unsubscribe(s)
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) subscribers() []*Subscription {
+	subs := make([]*Subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+func (b *Broadcaster) unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, s)
+	b.mu.Unlock()
+}
+
+// Subscription is a consumer of a Broadcaster. It has its own position
+// in the stream and its own buffer. The records it returns are shared
+// with the other subscribers and must not be modified.
+type Subscription struct {
+	b      *Broadcaster
+	size   int
+	policy SlowPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*Record
+	dropped uint64
+	closed  bool
+	err     error
+
+	pending []byte
+}
+
+// push queues a record, applying the slow policy. It returns false if
+// the subscriber shouldn't receive anything more.
+func (s *Subscription) push(rec *Record) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) >= s.size {
+		if s.closed || s.err != nil {
+			return false
+		}
+		switch s.policy {
+		case DropOldest:
+			s.queue[0] = nil
+			s.queue = s.queue[1:]
+			s.dropped++
+		case Disconnect:
+			s.err = ErrSlowSubscriber
+			s.cond.Broadcast()
+			return false
+		default:
+			s.cond.Wait()
+		}
+	}
+	if s.closed {
+		return false
+	}
+	s.queue = append(s.queue, rec)
+	s.cond.Broadcast()
+	return true
+}
+
+// end marks the end of the stream for this subscriber.
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
+
+// Next returns the next line for this subscriber, blocking until there
+// is one.
+func (s *Subscription) Next() (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && s.err == nil && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return nil, io.EOF
+	}
+	if len(s.queue) == 0 {
+		return nil, s.err
+	}
+	rec := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	s.cond.Broadcast()
+	return rec, nil
+}
+
+// Read reads the lines of this subscriber as a stream of bytes.
+func (s *Subscription) Read(b []byte) (int, error) {
+	if len(s.pending) == 0 {
+		rec, err := s.Next()
+		if err != nil {
+			return 0, err
+		}
+		s.pending = rec.Data
+	}
+	n := copy(b, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// Dropped returns how many lines were discarded because this subscriber
+// was too slow.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close unsubscribes from the Broadcaster. The underlying follower
+// keeps running for the other subscribers.
+func (s *Subscription) Close() error {
+	s.b.unsubscribe(s)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.queue = nil
+	s.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,88 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestBroadcasterFansOut(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.Follow(filename, true)
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		b := tailf.NewBroadcaster(follow)
+		defer b.Close()
+
+		subs := []*tailf.Subscription{
+			b.Subscribe(10, tailf.Block),
+			b.Subscribe(10, tailf.DropOldest),
+			b.Subscribe(10, tailf.Disconnect),
+		}
+
+		want := "shipper\nalerts\ndebug\n"
+		if _, err := file.WriteString(want); err != nil {
+			return err
+		}
+
+		for i, sub := range subs {
+			data := make([]byte, len(want))
+			if _, err := io.ReadFull(sub, data); err != nil {
+				return fmt.Errorf("subscriber %d: %v", i, err)
+			}
+			if string(data) != want {
+				t.Errorf("subscriber %d: want %q, got %q", i, want, data)
+			}
+		}
+		return nil
+	})
+}
+
+func TestBroadcasterSlowPolicies(t *testing.T) {
+	r, w := io.Pipe()
+	b := tailf.NewBroadcaster(r)
+
+	fast := b.Subscribe(10, tailf.Block)
+	dropper := b.Subscribe(2, tailf.DropOldest)
+	quitter := b.Subscribe(2, tailf.Disconnect)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, "line %d\n", i)
+		}
+		w.Close()
+	}()
+
+	all, err := ioutil.ReadAll(fast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "line 0\nline 1\nline 2\nline 3\nline 4\n"; string(all) != want {
+		t.Errorf("want %q, got %q", want, all)
+	}
+
+	kept, err := ioutil.ReadAll(dropper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "line 3\nline 4\n"; string(kept) != want {
+		t.Errorf("want %q, got %q", want, kept)
+	}
+	if n := dropper.Dropped(); n != 3 {
+		t.Errorf("want 3 dropped lines, got %d", n)
+	}
+
+	got, err := ioutil.ReadAll(quitter)
+	if err != tailf.ErrSlowSubscriber {
+		t.Errorf("want ErrSlowSubscriber, got %v", err)
+	}
+	if want := "line 0\nline 1\n"; string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,356 @@
+package tailf
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// fingerprintSize is how many bytes at the start of a file make its
+// fingerprint.
+const fingerprintSize = 1024
+
+// CatchUp is how a follower resuming from a checkpoint in a file that
+// was rotated away finds it among the rotated siblings of the file. It
+// then reads what it missed from the siblings, decompressing them if
+// they're gzip or zstd files, before going on with the file.
+type CatchUp struct {
+	// Patterns are the glob patterns of the rotated siblings,
+	// relative to the directory of the file, where {name} stands for
+	// the name of the file. Default to the names rotated files are
+	// given, numbered or dated and maybe compressed, like app.log.1,
+	// app.log.2.gz or app.log-20240101.zst, and not the other files
+	// named after the file, like its checkpoint.
+	Patterns []string
+}
+
+// rotationSuffix is the shape of what follows the name of a file in the
+// names of its rotated siblings, when there are no patterns.
+var rotationSuffix = regexp.MustCompile(`^[.-](\d+|\d{4}-\d{2}-\d{2}(-\d+)*)(\.gz|\.zst)?$`)
+
+// fingerprint is the hash of the first n bytes of a file, which tells
+// it apart from the others once its inode changed, like when it was
+// compressed.
+type fingerprint struct {
+	sum string
+	n   int
+}
+
+func fingerprintOf(r io.Reader) (fingerprint, error) {
+	buf := make([]byte, fingerprintSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fingerprint{}, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return fingerprint{sum: hex.EncodeToString(sum[:]), n: n}, nil
+}
+
+// fingerprintFile returns the fingerprint of the content of a file,
+// decompressed.
+func fingerprintFile(filename string) (fingerprint, error) {
+	r, err := openDecompressed(filename)
+	if err != nil {
+		return fingerprint{}, err
+	}
+	defer r.Close()
+	return fingerprintOf(r)
+}
+
+// fingerprintOfFile returns the fingerprint of a file the follower read,
+// if it knows it.
+func (f *Follower) fingerprintOfFile(id fileID) fingerprint {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fp := f.fingerprints[id]
+	if fp.n < fingerprintSize && id == f.pos.id {
+		// the file might have grown since
+		f.refreshFingerprint()
+		fp = f.fingerprints[id]
+	}
+	return fp
+}
+
+// refreshFingerprint computes the fingerprint of the current file. It
+// must be called with the lock held.
+func (f *Follower) refreshFingerprint() {
+	if fp, err := fingerprintOf(io.NewSectionReader(f.file, 0, fingerprintSize)); err == nil {
+		f.fingerprints[f.pos.id] = fp
+	}
+}
+
+// openDecompressed opens a file, decompressing it according to its
+// extension.
+func openDecompressed(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(filename) {
+	case ".gz":
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &decompressed{Reader: zr, file: file, close: zr.Close}, nil
+	case ".zst":
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &decompressed{Reader: zr, file: file, close: func() error { zr.Close(); return nil }}, nil
+	}
+	return file, nil
+}
+
+type decompressed struct {
+	io.Reader
+	file  *os.File
+	close func() error
+}
+
+func (d *decompressed) Close() error {
+	err := d.close()
+	if ferr := d.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// sibling is a rotated sibling of a followed file.
+type sibling struct {
+	path   string
+	id     fileID
+	offset int64
+}
+
+// rotatedSiblings returns the rotated siblings of a file, oldest first.
+func rotatedSiblings(filename string, c *CatchUp) ([]string, []os.FileInfo, error) {
+	patterns := c.Patterns
+	var shape *regexp.Regexp
+	if len(patterns) == 0 {
+		patterns, shape = []string{"{name}.*", "{name}-*"}, rotationSuffix
+	}
+	dir, name := filepath.Split(filename)
+	type rotated struct {
+		path string
+		fi   os.FileInfo
+	}
+	seen := make(map[string]bool)
+	var sibs []rotated
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, strings.Replace(pattern, "{name}", name, -1)))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range matches {
+			fi, err := os.Stat(path)
+			if seen[path] || err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			if shape != nil && !shape.MatchString(strings.TrimPrefix(filepath.Base(path), name)) {
+				continue
+			}
+			seen[path] = true
+			sibs = append(sibs, rotated{path, fi})
+		}
+	}
+	// logrotate keeps the modification time of what it rotates,
+	// compressed or not, which orders them whatever their names, and
+	// their names order those rotated at the same time
+	sort.Slice(sibs, func(i, j int) bool {
+		ti, tj := sibs[i].fi.ModTime(), sibs[j].fi.ModTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return rotatedBefore(sibs[i].path, sibs[j].path)
+	})
+
+	paths := make([]string, len(sibs))
+	infos := make([]os.FileInfo, len(sibs))
+	for i, s := range sibs {
+		paths[i], infos[i] = s.path, s.fi
+	}
+	return paths, infos, nil
+}
+
+// rotatedBefore tells if the sibling at path a was rotated before the
+// one at b, by their names: app.log.2 before app.log.1, and
+// app.log-20240101 before app.log-20240102.
+func rotatedBefore(a, b string) bool {
+	na, oka := rotationNumber(a)
+	nb, okb := rotationNumber(b)
+	if oka && okb && na != nb {
+		return na > nb
+	}
+	return a < b
+}
+
+// rotationNumber returns the number a rotated file was given, like 2
+// for app.log.2.gz.
+func rotationNumber(path string) (int, bool) {
+	switch ext := filepath.Ext(path); ext {
+	case ".gz", ".zst":
+		path = strings.TrimSuffix(path, ext)
+	}
+	digits := strings.TrimPrefix(filepath.Ext(path), ".")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// findCatchUp returns the rotated siblings holding what was missed
+// since the checkpoint, oldest first. It returns none if the file of
+// the checkpoint can't be found.
+func findCatchUp(filename string, cp *Checkpoint, c *CatchUp) ([]sibling, error) {
+	paths, infos, err := rotatedSiblings(filename, c)
+	if err != nil {
+		return nil, err
+	}
+	want := fileID{Dev: cp.Dev, Ino: cp.Ino}
+	for i, path := range paths {
+		if identify(infos[i]) != want && !matchFingerprint(path, cp) {
+			continue
+		}
+		siblings := toSiblings(paths[i:], infos[i:])
+		siblings[0].offset = cp.Offset
+		return siblings, nil
+	}
+	return nil, nil
+}
+
+// findHistory returns all the rotated siblings of a file, oldest first.
+func findHistory(filename string, c *CatchUp) ([]sibling, error) {
+	paths, infos, err := rotatedSiblings(filename, c)
+	if err != nil {
+		return nil, err
+	}
+	return toSiblings(paths, infos), nil
+}
+
+func toSiblings(paths []string, infos []os.FileInfo) []sibling {
+	siblings := make([]sibling, len(paths))
+	for i, path := range paths {
+		siblings[i] = sibling{path: path, id: identify(infos[i])}
+	}
+	return siblings
+}
+
+func matchFingerprint(path string, cp *Checkpoint) bool {
+	if cp.Fingerprint == "" || cp.FingerprintSize == 0 {
+		return false
+	}
+	r, err := openDecompressed(path)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	fp, err := fingerprintOf(io.LimitReader(r, int64(cp.FingerprintSize)))
+	return err == nil && fp.n == cp.FingerprintSize && fp.sum == cp.Fingerprint
+}
+
+// catchUp reads the rotated siblings of a file, one generation each.
+// The follower's lock guards what Position looks at.
+type catchUp struct {
+	siblings []sibling
+	r        io.ReadCloser
+	path     string
+	pos      position
+}
+
+// read reads from the siblings, and returns io.EOF once they're all
+// read.
+func (c *catchUp) read(f *Follower, b []byte) (int, position, error) {
+	for {
+		if c.r == nil {
+			if len(c.siblings) == 0 {
+				return 0, position{}, io.EOF
+			}
+			if err := c.open(f); err != nil {
+				return 0, position{}, err
+			}
+		}
+		n, err := c.r.Read(b)
+		f.mu.Lock()
+		at := c.pos
+		c.pos.offset += int64(n)
+		if err == io.EOF {
+			_ = c.r.Close()
+			c.r = nil
+			c.pos.gen++
+			err = nil
+		}
+		f.mu.Unlock()
+		if n != 0 || err != nil {
+			return n, at, err
+		}
+	}
+}
+
+// open opens the next sibling, at the offset to start reading it from.
+func (c *catchUp) open(f *Follower) error {
+	s := c.siblings[0]
+	f.opts.Logger.Debug("tailf: catching up with rotated file", "path", f.filename, "rotated", s.path, "offset", s.offset)
+
+	if fp, err := fingerprintFile(s.path); err == nil {
+		f.mu.Lock()
+		f.fingerprints[s.id] = fp
+		f.mu.Unlock()
+	}
+	r, err := openSibling(s)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c.siblings = c.siblings[1:]
+	if err != nil {
+		return err
+	}
+	c.r, c.path = r, s.path
+	c.pos.id, c.pos.offset = s.id, s.offset
+	return nil
+}
+
+// openSibling opens a sibling, at the offset to start reading it from.
+func openSibling(s sibling) (io.ReadCloser, error) {
+	r, err := openDecompressed(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := r.(io.Seeker); ok {
+		_, err = seeker.Seek(s.offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(ioutil.Discard, r, s.offset)
+	}
+	if err != nil && err != io.EOF {
+		_ = r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// current returns the sibling being read, or the next one, and where in
+// it. It returns false once they're all read.
+func (c *catchUp) current() (string, position, bool) {
+	if c.r != nil {
+		return c.path, c.pos, true
+	}
+	if len(c.siblings) == 0 {
+		return "", position{}, false
+	}
+	s := c.siblings[0]
+	return s.path, position{gen: c.pos.gen, id: s.id, offset: s.offset}, true
+}
@@ -0,0 +1,209 @@
+package tailf_test
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compress replaces a file with its compressed version, like logrotate
+// does.
+func compress(filename, ext string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(filename + ext)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	var w io.WriteCloser
+	switch ext {
+	case ".gz":
+		w = gzip.NewWriter(out)
+	case ".zst":
+		if w, err = zstd.NewWriter(out); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+func TestCatchUpThroughCompressedRotation(t *testing.T) {
+	for _, ext := range []string{".gz", ".zst"} {
+		t.Run(ext, func(t *testing.T) {
+			dir, err := ioutil.TempDir(os.TempDir(), "tailf_test_dir")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "app.log")
+			store := tailf.FileCheckpointStore(filepath.Join(dir, "checkpoint"))
+			opts := tailf.Options{FromStart: true, Checkpoint: store, CatchUp: &tailf.CatchUp{}}
+
+			if err := ioutil.WriteFile(filename, []byte("one\ntwo\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			follow, err := tailf.FollowWithOptions(filename, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec, err := follow.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rec.Ack(); err != nil {
+				t.Fatal(err)
+			}
+			follow.Close()
+
+			// what happened while not following
+			if err := appendFile(filename, "three\n"); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Rename(filename, filename+".1"); err != nil {
+				t.Fatal(err)
+			}
+			if err := compress(filename+".1", ext); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filename, []byte("four\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			follow, err = tailf.FollowWithOptions(filename, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer follow.Close()
+			for _, want := range []string{"two", "three", "four"} {
+				rec, err := follow.Next()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(rec.Line()) != want {
+					t.Errorf("want %q, got %q", want, rec.Line())
+				}
+				if err := rec.Ack(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestFollowWithHistory(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailf_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	// oldest first, as logrotate would have left them
+	old := time.Now().Add(-time.Hour)
+	for i, rotated := range []struct{ name, content, ext string }{
+		{"app.log.3", "one\n", ".gz"},
+		{"app.log.2", "two\n", ".zst"},
+		{"app.log.1", "three\n", ""},
+	} {
+		path := filepath.Join(dir, rotated.name)
+		if err := ioutil.WriteFile(path, []byte(rotated.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if rotated.ext != "" {
+			if err := compress(path, rotated.ext); err != nil {
+				t.Fatal(err)
+			}
+			path += rotated.ext
+		}
+		mtime := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filename, []byte("four\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a checkpoint kept next to the file isn't one of its rotations
+	store := tailf.FileCheckpointStore(filename + ".pos")
+	if err := store.Save(tailf.Checkpoint{Path: "elsewhere"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename+".pos12345", []byte("tmp\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	follow, err := tailf.FollowWithHistory(filename, tailf.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follow.Close()
+	for _, want := range []string{"one", "two", "three", "four"} {
+		rec, err := follow.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rec.Line()) != want {
+			t.Errorf("want %q, got %q", want, rec.Line())
+		}
+	}
+}
+
+func TestFollowWithHistorySameModTime(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailf_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	// restored from a backup, they all have the same modification time
+	mtime := time.Now().Add(-time.Hour)
+	for _, rotated := range []struct{ name, content string }{
+		{"app.log.1", "four\n"},
+		{"app.log.2", "three\n"},
+		{"app.log.3", "two\n"},
+		{"app.log.10", "one\n"},
+	} {
+		path := filepath.Join(dir, rotated.name)
+		if err := ioutil.WriteFile(path, []byte(rotated.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filename, []byte("five\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	follow, err := tailf.FollowWithHistory(filename, tailf.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follow.Close()
+	for _, want := range []string{"one", "two", "three", "four", "five"} {
+		rec, err := follow.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rec.Line()) != want {
+			t.Errorf("want %q, got %q", want, rec.Line())
+		}
+	}
+}
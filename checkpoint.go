@@ -0,0 +1,216 @@
+package tailf
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint is a position in a followed file, up to which everything
+// was processed.
+type Checkpoint struct {
+	Path string `json:"path"`
+	// Dev and Ino identify the file the offset is in, which might
+	// have been rotated away from Path since.
+	Dev    uint64 `json:"dev"`
+	Ino    uint64 `json:"ino"`
+	Offset int64  `json:"offset"`
+	// Fingerprint is the SHA-256 of the first FingerprintSize bytes
+	// of the file, which finds it once compressed.
+	Fingerprint     string `json:"fingerprint,omitempty"`
+	FingerprintSize int    `json:"fingerprint_size,omitempty"`
+}
+
+// CheckpointStore persists the checkpoint of a follower.
+type CheckpointStore interface {
+	// Load returns the last checkpoint saved, or nil if there is
+	// none.
+	Load() (*Checkpoint, error)
+	Save(cp Checkpoint) error
+}
+
+// FileCheckpointStore keeps a checkpoint in a file, as JSON.
+type FileCheckpointStore string
+
+// Load reads the checkpoint from the file. A missing file isn't an
+// error, it means there's no checkpoint yet.
+func (s FileCheckpointStore) Load() (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(string(s))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cp := new(Checkpoint)
+	return cp, json.Unmarshal(data, cp)
+}
+
+// Save replaces the checkpoint in the file. A crash while saving leaves
+// the previous checkpoint in place.
+func (s FileCheckpointStore) Save(cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(string(s)), filepath.Base(string(s)))
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), string(s))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// defaultCheckpointInterval is how often a checkpoint is saved at most,
+// unless the options say otherwise.
+const defaultCheckpointInterval = time.Second
+
+// ackTracker follows which records handed out by a follower were
+// acknowledged, and saves the position before the oldest one that
+// wasn't. It's saved at most once per interval, the latest position
+// winning, instead of once per record.
+type ackTracker struct {
+	path     string
+	store    CheckpointStore
+	interval time.Duration
+	// fingerprint returns the fingerprint of a file, if known.
+	fingerprint func(id fileID) fingerprint
+
+	mu        sync.Mutex
+	pending   []*ack
+	committed Checkpoint
+	saved     Checkpoint
+	scheduled bool
+	// err is the error of the last save, returned by the next acks.
+	err error
+
+	// saving serializes the saves.
+	saving sync.Mutex
+}
+
+// ack is the acknowledgement handle of a record.
+type ack struct {
+	t    *ackTracker
+	id   fileID
+	from int64
+	to   int64
+	done bool
+}
+
+func newAckTracker(path string, store CheckpointStore, interval time.Duration, id fileID, offset int64) *ackTracker {
+	if interval <= 0 {
+		interval = defaultCheckpointInterval
+	}
+	cp := Checkpoint{Path: path, Dev: id.Dev, Ino: id.Ino, Offset: offset}
+	return &ackTracker{
+		path:      path,
+		store:     store,
+		interval:  interval,
+		committed: cp,
+		saved:     cp,
+	}
+}
+
+// track registers a record spanning [from, to) in the file identified
+// by id. Records must be tracked in the order they're read.
+func (t *ackTracker) track(id fileID, from, to int64) *ack {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a := &ack{t: t, id: id, from: from, to: to}
+	t.pending = append(t.pending, a)
+	return a
+}
+
+func (a *ack) ack() error {
+	t := a.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if a.done {
+		return nil
+	}
+	a.done = true
+
+	if t.pending[0] != a {
+		// an older record is still pending, nothing to commit
+		return nil
+	}
+	last := a
+	for len(t.pending) != 0 && t.pending[0].done {
+		last = t.pending[0]
+		t.pending[0] = nil
+		t.pending = t.pending[1:]
+	}
+
+	// commit up to the oldest pending record, or past the last one
+	cp := Checkpoint{Path: t.path, Dev: last.id.Dev, Ino: last.id.Ino, Offset: last.to}
+	if len(t.pending) != 0 {
+		next := t.pending[0]
+		cp.Dev, cp.Ino, cp.Offset = next.id.Dev, next.id.Ino, next.from
+	}
+	if t.fingerprint != nil {
+		fp := t.fingerprint(fileID{Dev: cp.Dev, Ino: cp.Ino})
+		cp.Fingerprint, cp.FingerprintSize = fp.sum, fp.n
+	}
+	if cp == t.committed {
+		return t.err
+	}
+	t.committed = cp
+	if !t.scheduled {
+		t.scheduled = true
+		time.AfterFunc(t.interval, func() { _ = t.flush() })
+	}
+	return t.err
+}
+
+// flush saves the last position committed, if it wasn't yet.
+func (t *ackTracker) flush() error {
+	t.saving.Lock()
+	defer t.saving.Unlock()
+
+	t.mu.Lock()
+	cp := t.committed
+	t.scheduled = false
+	if cp == t.saved {
+		t.mu.Unlock()
+		return nil
+	}
+	t.mu.Unlock()
+
+	err := t.store.Save(cp)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+	if err == nil {
+		t.saved = cp
+	}
+	return err
+}
+
+// inFile tells if the checkpoint is in a file. Its fingerprint, when it
+// has one, tells the file apart from another one that reused its inode.
+func (cp *Checkpoint) inFile(file *os.File, fi os.FileInfo) bool {
+	if identify(fi) != (fileID{Dev: cp.Dev, Ino: cp.Ino}) || cp.Offset > fi.Size() {
+		return false
+	}
+	if cp.FingerprintSize == 0 {
+		return true
+	}
+	fp, err := fingerprintOf(io.NewSectionReader(file, 0, int64(cp.FingerprintSize)))
+	return err == nil && fp.n == cp.FingerprintSize && fp.sum == cp.Fingerprint
+}
@@ -0,0 +1,160 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestCheckpointOnlyMovesPastAcknowledgedRecords(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		store := tailf.FileCheckpointStore(filepath.Join(filepath.Dir(filename), "checkpoint"))
+		opts := tailf.Options{FromStart: true, Checkpoint: store}
+
+		follow, err := tailf.FollowWithOptions(filename, opts)
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		if _, err := file.WriteString("one\ntwo\nthree\n"); err != nil {
+			return err
+		}
+
+		var recs []*tailf.Record
+		for i := 0; i < 3; i++ {
+			rec, err := follow.Next()
+			if err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+		}
+
+		if err := recs[1].Ack(); err != nil {
+			return err
+		}
+		if cp, err := store.Load(); err != nil || cp != nil {
+			t.Errorf("want no checkpoint while the first record is pending, got %+v (%v)", cp, err)
+		}
+
+		if err := recs[0].Ack(); err != nil {
+			return err
+		}
+		// the last position is saved on close
+		follow.Close()
+		cp, err := store.Load()
+		if err != nil {
+			return err
+		}
+		if cp == nil || cp.Offset != 8 {
+			t.Errorf("want checkpoint at 8, got %+v", cp)
+		}
+
+		// after a crash, resume at the first record that wasn't acknowledged
+		follow, err = tailf.FollowWithOptions(filename, opts)
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Data) != "three\n" || rec.FileOffset != 8 {
+			t.Errorf("want to resume at three@8, got %q@%d", rec.Data, rec.FileOffset)
+		}
+		return nil
+	})
+}
+
+// countingStore counts the checkpoints saved.
+type countingStore struct {
+	mu    sync.Mutex
+	saves int
+	last  tailf.Checkpoint
+}
+
+func (s *countingStore) Load() (*tailf.Checkpoint, error) { return nil, nil }
+
+func (s *countingStore) Save(cp tailf.Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
+	s.last = cp
+	return nil
+}
+
+func TestCheckpointSavesInBatches(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		store := &countingStore{}
+		opts := tailf.Options{FromStart: true, Checkpoint: store, CheckpointInterval: 20 * time.Millisecond}
+		follow, err := tailf.FollowWithOptions(filename, opts)
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		if _, err := file.WriteString(strings.Repeat("line\n", 100)); err != nil {
+			return err
+		}
+
+		// reading through Read acknowledges every line
+		if _, err := io.ReadFull(follow, make([]byte, 500)); err != nil {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if store.last.Offset != 500 {
+			t.Errorf("want the checkpoint saved at 500, got %+v", store.last)
+		}
+		if store.saves > 10 {
+			t.Errorf("want the checkpoint saved once in a while, saved %d times for 100 lines", store.saves)
+		}
+		return nil
+	})
+}
+
+func TestRecordsTellTheirGeneration(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if _, err := file.WriteString("old\nunterminated"); err != nil {
+			return err
+		}
+		if rec, err := follow.Next(); err != nil || string(rec.Data) != "old\n" {
+			return fmt.Errorf("want old line, got %v (%v)", rec, err)
+		}
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte("new\n"), 0600); err != nil {
+			return err
+		}
+
+		for _, want := range []struct {
+			data   string
+			gen    uint64
+			offset int64
+		}{{"unterminated", 0, 4}, {"new\n", 1, 0}} {
+			rec, err := follow.Next()
+			if err != nil {
+				return err
+			}
+			if string(rec.Data) != want.data || rec.Generation != want.gen || rec.FileOffset != want.offset {
+				t.Errorf("want %q@%d:%d, got %q@%d:%d", want.data, want.gen, want.offset, rec.Data, rec.Generation, rec.FileOffset)
+			}
+		}
+		return nil
+	})
+}
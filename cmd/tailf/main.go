@@ -0,0 +1,100 @@
+// Command tailf follows a file, like `tail -f` does.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// regexps is a flag that can be repeated.
+type regexps []*regexp.Regexp
+
+func (r *regexps) String() string {
+	var s []string
+	for _, re := range *r {
+		s = append(s, re.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func (r *regexps) Set(v string) error {
+	re, err := regexp.Compile(v)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, re)
+	return nil
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tailf: ")
+
+	var (
+		fromStart = flag.Bool("from-start", false, "read the file from its start instead of its end")
+		since     = flag.String("since", "", "start with the lines logged since a time, like 10:42, 2006-01-02T15:04:05Z or 15m for 15 minutes ago")
+		grep      regexps
+		grepV     regexps
+	)
+	flag.Var(&grep, "grep", "only print the lines matching this regexp (repeatable)")
+	flag.Var(&grepV, "grep-v", "don't print the lines matching this regexp (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tailf [flags] FILE\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	filename := flag.Arg(0)
+
+	opts := tailf.Options{FromStart: *fromStart}
+	if *since != "" {
+		t, err := parseSince(*since, time.Now())
+		if err != nil {
+			log.Fatalf("invalid --since: %v", err)
+		}
+		opts.Since = t
+	}
+	if len(grep) != 0 || len(grepV) != 0 {
+		opts.Filter = &tailf.Filter{Include: grep, Exclude: grepV}
+	}
+
+	follow, err := tailf.FollowWithOptions(filename, opts)
+	if err != nil {
+		log.Fatalf("couldn't follow %q: %v", filename, err)
+	}
+	defer follow.Close()
+
+	if _, err := io.Copy(os.Stdout, follow); err != nil {
+		log.Fatalf("couldn't read from follower: %v", err)
+	}
+}
+
+// parseSince reads a time as a duration before now, a time of the day
+// or a full timestamp.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, now.Location())
+}
@@ -0,0 +1,200 @@
+package tailf
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Dedup collapses the repeats of a line, like syslog does: the first
+// line goes through, and the identical lines following it are handed
+// out as a single record telling how many times it was repeated.
+type Dedup struct {
+	// Mask compares the lines with their numbers masked, so that
+	// lines only differing by a counter or a timestamp are repeats.
+	Mask bool
+	// Window is how long after a line its repeats are collapsed. A
+	// repeat past the window goes through as a new line. Defaults to
+	// no limit.
+	Window time.Duration
+	// FlushInterval is the longest repeats are held before being
+	// handed out, when no other line comes. Defaults to 30s.
+	FlushInterval time.Duration
+}
+
+// key is what tells repeated lines apart.
+func (d *Dedup) key(line []byte) []byte {
+	line = trimEOL(line)
+	if !d.Mask {
+		return line
+	}
+	key := make([]byte, 0, len(line))
+	for i, c := range line {
+		if !isDigit(c) {
+			key = append(key, c)
+		} else if i == 0 || !isDigit(line[i-1]) {
+			key = append(key, '#')
+		}
+	}
+	return key
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+type recordResult struct {
+	rec *Record
+	err error
+}
+
+// dedupRecords collapses the repeated records of src. It reads ahead
+// of its reader, to hand out the repeats it holds once they're due.
+type dedupRecords struct {
+	src   RecordReader
+	dedup *Dedup
+	done  <-chan struct{}
+	// seeks, if not nil, counts the seeks of the follower, which
+	// make the records read ahead before them stale.
+	seeks *int32
+	// seeked is the seeks of the last record, a seek starting over
+	// the comparisons.
+	seeked int32
+
+	recc    chan recordResult
+	err     error
+	last    []byte
+	lastAt  time.Time
+	queued  *Record
+	pending *Record
+	repeats int
+	flushAt time.Time
+}
+
+func newDedupRecords(src RecordReader, dedup *Dedup, done <-chan struct{}) *dedupRecords {
+	d := &dedupRecords{src: src, dedup: dedup, done: done, recc: make(chan recordResult)}
+	go d.readAhead()
+	return d
+}
+
+func (d *dedupRecords) readAhead() {
+	for {
+		rec, err := d.src.Next()
+		select {
+		case d.recc <- recordResult{rec, err}:
+		case <-d.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *dedupRecords) Next() (*Record, error) {
+	for {
+		if d.queued != nil {
+			rec := d.queued
+			d.queued = nil
+			if d.stale(rec) {
+				if err := rec.Ack(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return rec, nil
+		}
+		if d.err != nil {
+			if d.pending != nil {
+				return d.flush(), nil
+			}
+			return nil, d.err
+		}
+
+		res, due := d.wait()
+		if due {
+			return d.flush(), nil
+		}
+		if res.err != nil {
+			d.err = res.err
+			continue
+		}
+
+		rec, now := res.rec, time.Now()
+		if d.stale(rec) {
+			if err := rec.Ack(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if rec.seeks != d.seeked {
+			d.seeked, d.last = rec.seeks, nil
+		}
+		key := d.dedup.key(rec.Data)
+		window := d.dedup.Window
+		if d.last != nil && bytes.Equal(key, d.last) && (window <= 0 || now.Sub(d.lastAt) < window) {
+			if err := d.hold(rec, now); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		d.last, d.lastAt = key, now
+		if d.pending != nil {
+			d.queued = rec
+			return d.flush(), nil
+		}
+		return rec, nil
+	}
+}
+
+// stale tells if a record was read before the follower seeked.
+func (d *dedupRecords) stale(rec *Record) bool {
+	return d.seeks != nil && rec.seeks != atomic.LoadInt32(d.seeks)
+}
+
+// wait returns the next record of the source, or tells the repeats held
+// are due.
+func (d *dedupRecords) wait() (recordResult, bool) {
+	var duec <-chan time.Time
+	if d.pending != nil {
+		t := time.NewTimer(time.Until(d.flushAt))
+		defer t.Stop()
+		duec = t.C
+	}
+	select {
+	case res := <-d.recc:
+		return res, false
+	case <-duec:
+		return recordResult{}, true
+	case <-d.done:
+		// the follower is closed, the source has nothing more
+		return recordResult{err: io.EOF}, false
+	}
+}
+
+// hold keeps a repeat until it's due. It stands for the repeats held
+// before it, which are done with.
+func (d *dedupRecords) hold(rec *Record, now time.Time) error {
+	if d.pending != nil {
+		if err := d.pending.Ack(); err != nil {
+			return err
+		}
+	} else {
+		interval := d.dedup.FlushInterval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		d.flushAt = now.Add(interval)
+	}
+	d.pending = rec
+	d.repeats++
+	return nil
+}
+
+// flush returns the record standing for the repeats held.
+func (d *dedupRecords) flush() *Record {
+	rec := d.pending
+	rec.Repeated = d.repeats
+	d.pending, d.repeats = nil, 0
+	return rec
+}
@@ -0,0 +1,77 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestDedupCollapsesRepeats(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		lines := "boom\nboom\nboom\nok\n" +
+			"took 12ms at 10:11:12\ntook 7ms at 10:11:13\nbye\n"
+		if _, err := file.WriteString(lines); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Dedup:     &tailf.Dedup{Mask: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		want := []struct {
+			line     string
+			repeated int
+		}{
+			{"boom", 0},
+			{"boom", 2},
+			{"ok", 0},
+			{"took 12ms at 10:11:12", 0},
+			{"took 7ms at 10:11:13", 1},
+			{"bye", 0},
+		}
+		for _, w := range want {
+			rec, err := follow.Next()
+			if err != nil {
+				return err
+			}
+			if string(rec.Line()) != w.line || rec.Repeated != w.repeated {
+				t.Errorf("want %q repeated %d times, got %q repeated %d times", w.line, w.repeated, rec.Line(), rec.Repeated)
+			}
+		}
+		return nil
+	})
+}
+
+func TestDedupFlushesHeldRepeats(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("boom\nboom\nboom\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Dedup:     &tailf.Dedup{FlushInterval: 20 * time.Millisecond},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		want := "boom\nlast message repeated 2 times\n"
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,100 @@
+package tailf
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a followed file.
+type Encoding int
+
+const (
+	// Raw hands out the bytes of the file as they are.
+	Raw Encoding = iota
+	// UTF8 hands out the file without its byte order mark.
+	UTF8
+	// UTF16LE, UTF16BE and Latin1 transcode the file to UTF-8.
+	UTF16LE
+	UTF16BE
+	Latin1
+)
+
+var boms = []struct {
+	mark []byte
+	enc  Encoding
+}{
+	{[]byte{0xef, 0xbb, 0xbf}, UTF8},
+	{[]byte{0xff, 0xfe}, UTF16LE},
+	{[]byte{0xfe, 0xff}, UTF16BE},
+}
+
+// detectBOM returns the encoding told by the byte order mark at the
+// start of data, and its length. It returns more as true when data is
+// too short to tell yet.
+func detectBOM(data []byte) (enc Encoding, n int, more bool) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(data, bom.mark) {
+			return bom.enc, len(bom.mark), false
+		}
+		if len(data) < len(bom.mark) && bytes.HasPrefix(bom.mark, data) {
+			more = true
+		}
+	}
+	return Raw, 0, more
+}
+
+// newline returns the length of the first line of data, newline
+// included, or -1 if it isn't terminated.
+func (e Encoding) newline(data []byte) int {
+	var nl [2]byte
+	switch e {
+	case UTF16LE:
+		nl = [2]byte{'\n', 0}
+	case UTF16BE:
+		nl = [2]byte{0, '\n'}
+	default:
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			return i + 1
+		}
+		return -1
+	}
+	// only look at whole code units
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == nl[0] && data[i+1] == nl[1] {
+			return i + 2
+		}
+	}
+	return -1
+}
+
+// decode transcodes data to UTF-8. Invalid or incomplete sequences
+// become U+FFFD.
+func (e Encoding) decode(data []byte) []byte {
+	switch e {
+	case Latin1:
+		out := make([]byte, 0, 2*len(data))
+		for _, c := range data {
+			out = append(out, string(rune(c))...)
+		}
+		return out
+	case UTF16LE, UTF16BE:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			hi, lo := data[2*i+1], data[2*i]
+			if e == UTF16BE {
+				hi, lo = lo, hi
+			}
+			units[i] = uint16(hi)<<8 | uint16(lo)
+		}
+		out := make([]byte, 0, len(data))
+		for _, r := range utf16.Decode(units) {
+			out = append(out, string(r)...)
+		}
+		if len(data)%2 != 0 {
+			out = append(out, string(utf8.RuneError)...)
+		}
+		return out
+	}
+	return data
+}
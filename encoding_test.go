@@ -0,0 +1,104 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestEncodingSplitAcrossReads(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		// a BOM, overriding the encoding of the options, then "é😀\n"
+		// in UTF-16LE, cut in the middle of the surrogate pair
+		data := []byte{0xff, 0xfe, 0xe9, 0x00, 0x3d, 0xd8, 0x00, 0xde, '\n', 0x00}
+		if _, err := file.Write(data[:5]); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, Encoding: tailf.Latin1})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_, _ = file.Write(data[5:])
+		}()
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if got := string(rec.Data); got != "é😀\n" {
+			t.Errorf("want %q, got %q", "é😀\n", got)
+		}
+		if rec.FileOffset != 2 {
+			t.Errorf("want the line at offset 2, after the BOM, got %d", rec.FileOffset)
+		}
+		return nil
+	})
+}
+
+func TestEncodingLatin1(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.Write([]byte("caf\xe9\n")); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, Encoding: tailf.Latin1})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		got := make([]byte, len("café\n"))
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "café\n" {
+			t.Errorf("want %q, got %q", "café\n", got)
+		}
+		return nil
+	})
+}
+
+func TestEncodingDetectedAfterTruncation(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		// "ab\n" in UTF-16LE, and half of a code unit
+		if _, err := file.Write([]byte{0xff, 0xfe, 'a', 0, 'b', 0, '\n', 0, 'c'}); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, Encoding: tailf.UTF16LE})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if got := string(rec.Data); got != "ab\n" {
+			t.Errorf("want %q, got %q", "ab\n", got)
+		}
+
+		// now "d\n" in UTF-16BE
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte{0xfe, 0xff, 0, 'd', 0, '\n'}, 0); err != nil {
+			return err
+		}
+		for _, want := range []string{"�", "d\n"} {
+			rec, err := follow.Next()
+			if err != nil {
+				return err
+			}
+			if got := string(rec.Data); got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package tailf
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTruncated is matched by errors.Is for any error telling the
+	// followed file was truncated.
+	ErrTruncated = errors.New("file was truncated")
+	// ErrRemoved is matched by errors.Is for any error telling the
+	// followed file was removed.
+	ErrRemoved = errors.New("file was removed")
+	// ErrDirRemoved is the cause of an ErrFileRemoved when the
+	// directory of the file was removed or renamed.
+	ErrDirRemoved = errors.New("directory was removed")
+)
+
+// ErrFileTruncated signifies the underlying file of a tailf.Follower
+// has been truncated.
+type ErrFileTruncated struct {
+	Path  string
+	Inode uint64
+	// Offset is how far the file had been read, and Size how large it
+	// was found after the truncation.
+	Offset int64
+	Size   int64
+	// Err is the cause of the error, if any.
+	Err error
+}
+
+func (e ErrFileTruncated) Error() string {
+	return fmt.Sprintf("file (%s) was truncated to %d bytes, after %d were read", e.Path, e.Size, e.Offset)
+}
+
+func (e ErrFileTruncated) Unwrap() error { return e.Err }
+
+// Is tells the error matches ErrTruncated.
+func (e ErrFileTruncated) Is(target error) bool { return target == ErrTruncated }
+
+// ErrFileRemoved signifies the underlying file of a tailf.Follower
+// has been removed.
+type ErrFileRemoved struct {
+	Path  string
+	Inode uint64
+	// Offset is how far the file had been read.
+	Offset int64
+	// Err is the cause of the error, if any.
+	Err error
+}
+
+func (e ErrFileRemoved) Error() string {
+	return fmt.Sprintf("file was removed: %v", e.Path)
+}
+
+func (e ErrFileRemoved) Unwrap() error { return e.Err }
+
+// Is tells the error matches ErrRemoved.
+func (e ErrFileRemoved) Is(target error) bool { return target == ErrRemoved }
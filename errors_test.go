@@ -0,0 +1,29 @@
+package tailf_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestErrorsMatchTheirSentinel(t *testing.T) {
+	truncated := fmt.Errorf("reading: %w", tailf.ErrFileTruncated{Path: "/var/log/app.log", Inode: 42, Offset: 10, Size: 2})
+	if !errors.Is(truncated, tailf.ErrTruncated) || errors.Is(truncated, tailf.ErrRemoved) {
+		t.Errorf("want %v to only be a truncation", truncated)
+	}
+	var terr tailf.ErrFileTruncated
+	if !errors.As(truncated, &terr) || terr.Inode != 42 || terr.Offset != 10 {
+		t.Errorf("want the truncation details, got %+v", terr)
+	}
+
+	removed := tailf.ErrFileRemoved{Path: "/var/log/app.log", Err: os.ErrNotExist}
+	if !errors.Is(removed, tailf.ErrRemoved) || errors.Is(removed, tailf.ErrTruncated) {
+		t.Errorf("want %v to only be a removal", removed)
+	}
+	if !errors.Is(removed, os.ErrNotExist) {
+		t.Errorf("want %v to unwrap to its cause", removed)
+	}
+}
@@ -0,0 +1,31 @@
+package tailf
+
+import "gopkg.in/fsnotify.v1"
+
+// SetReadDirect turns the direct reads of a follower behind its file
+// on or off.
+func SetReadDirect(f *Follower, on bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readDirect = on
+}
+
+// InjectWatchError hands an error to a follower as if its watch
+// reported it.
+func InjectWatchError(f *Follower, err error) {
+	f.mu.Lock()
+	watch := f.watch
+	f.mu.Unlock()
+	select {
+	case watch.Errors <- err:
+	case <-f.done:
+	}
+}
+
+// FailWatchRebuilds has the rebuilds of the watch of a follower fail
+// with err.
+func FailWatchRebuilds(f *Follower, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.newWatcher = func() (*fsnotify.Watcher, error) { return nil, err }
+}
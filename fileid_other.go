@@ -0,0 +1,17 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package tailf
+
+import "os"
+
+// fileID tells a file apart from the others, even after a rename. It
+// is always empty on this platform.
+type fileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+func identify(fi os.FileInfo) fileID {
+	return fileID{}
+}
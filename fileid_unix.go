@@ -0,0 +1,23 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package tailf
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID tells a file apart from the others, even after a rename.
+type fileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+func identify(fi os.FileInfo) fileID {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}
+	}
+	return fileID{Dev: uint64(st.Dev), Ino: uint64(st.Ino)}
+}
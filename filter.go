@@ -0,0 +1,222 @@
+package tailf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// Filter selects the lines of a followed file worth reading. A line
+// is kept when it passes every criteria that is set.
+type Filter struct {
+	// Include keeps the lines matching at least one of the regexps.
+	Include []*regexp.Regexp
+	// Exclude drops the lines matching any of the regexps.
+	Exclude []*regexp.Regexp
+	// Contains keeps the lines containing at least one of the
+	// literal strings. All the strings are searched for in a single
+	// pass over the line, so long lists remain cheap.
+	Contains []string
+	// Fields keeps the lines whose parsed fields match all the
+	// predicates.
+	Fields []FieldMatch
+	// Parse turns a line in fields for the Fields predicates.
+	// Defaults to ParseKeyValue.
+	Parse func(line []byte) map[string]string
+
+	once     sync.Once
+	contains *acMatcher
+}
+
+// FieldMatch is a predicate on a field of a parsed line.
+type FieldMatch struct {
+	Name string
+	// Value must match the field's value. If nil, the field only
+	// needs to be present.
+	Value *regexp.Regexp
+}
+
+// Match tells if a line, with or without its line ending, passes
+// the filter.
+func (f *Filter) Match(line []byte) bool {
+	f.once.Do(func() {
+		if len(f.Contains) != 0 {
+			f.contains = newACMatcher(f.Contains)
+		}
+	})
+	line = trimEOL(line)
+
+	for _, re := range f.Exclude {
+		if re.Match(line) {
+			return false
+		}
+	}
+	if len(f.Include) != 0 && !matchAny(f.Include, line) {
+		return false
+	}
+	if f.contains != nil && !f.contains.match(line) {
+		return false
+	}
+	if len(f.Fields) != 0 && !f.matchFields(line) {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) matchFields(line []byte) bool {
+	parse := f.Parse
+	if parse == nil {
+		parse = ParseKeyValue
+	}
+	fields := parse(line)
+	for _, fm := range f.Fields {
+		v, ok := fields[fm.Name]
+		if !ok {
+			return false
+		}
+		if fm.Value != nil && !fm.Value.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(res []*regexp.Regexp, line []byte) bool {
+	for _, re := range res {
+		if re.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewFilterReader returns a reader that only lets through the lines
+// of r that match the filter.
+func NewFilterReader(r io.Reader, filter *Filter) io.Reader {
+	return &recordBytes{src: &filterRecords{src: NewLineReader(r), filter: filter}}
+}
+
+// filterRecords only lets through the records matching the filter. The
+// others are acknowledged right away, there's nothing more to do with
+// them.
+type filterRecords struct {
+	src    RecordReader
+	filter *Filter
+}
+
+func (fr *filterRecords) Next() (*Record, error) {
+	for {
+		rec, err := fr.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if fr.filter.Match(rec.Data) {
+			return rec, nil
+		}
+		if err := rec.Ack(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// recordBytes reads records as a stream of bytes.
+type recordBytes struct {
+	src     RecordReader
+	pending []byte
+}
+
+func (rb *recordBytes) Read(b []byte) (int, error) {
+	if len(rb.pending) == 0 {
+		rec, err := rb.src.Next()
+		if err != nil {
+			return 0, err
+		}
+		// the bytes are consumed as soon as they're read
+		if err := rec.Ack(); err != nil {
+			return 0, err
+		}
+		rb.pending = rec.Data
+		if rec.Repeated != 0 {
+			rb.pending = []byte(fmt.Sprintf("last message repeated %d times\n", rec.Repeated))
+		}
+	}
+	n := copy(b, rb.pending)
+	rb.pending = rb.pending[n:]
+	return n, nil
+}
+
+// ParseKeyValue parses the `key=value` pairs of a line, logfmt style.
+// Values can be double quoted to hold spaces.
+func ParseKeyValue(line []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(line) != 0 {
+		line = bytes.TrimLeft(line, " \t")
+		eq := bytes.IndexByte(line, '=')
+		sp := bytes.IndexAny(line, " \t")
+		if eq < 0 || (sp >= 0 && sp < eq) {
+			// a bare word, skip it
+			if sp < 0 {
+				break
+			}
+			line = line[sp:]
+			continue
+		}
+		key := string(line[:eq])
+		line = line[eq+1:]
+
+		var value []byte
+		if len(line) != 0 && line[0] == '"' {
+			value, line = parseQuoted(line)
+		} else if sp := bytes.IndexAny(line, " \t"); sp >= 0 {
+			value, line = line[:sp], line[sp:]
+		} else {
+			value, line = line, nil
+		}
+		fields[key] = string(value)
+	}
+	return fields
+}
+
+func parseQuoted(line []byte) (value, rest []byte) {
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			var s string
+			if err := json.Unmarshal(line[:i+1], &s); err != nil {
+				return line[1:i], line[i+1:]
+			}
+			return []byte(s), line[i+1:]
+		}
+	}
+	// unterminated, take it all
+	return line[1:], nil
+}
+
+// ParseJSON parses a line holding a JSON object. The top level values
+// are formatted as strings.
+func ParseJSON(line []byte) map[string]string {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(line, &obj); err != nil {
+		return nil
+	}
+	fields := make(map[string]string, len(obj))
+	for k, v := range obj {
+		switch v := v.(type) {
+		case string:
+			fields[k] = v
+		case nil:
+			fields[k] = ""
+		case map[string]interface{}, []interface{}:
+			data, _ := json.Marshal(v)
+			fields[k] = string(data)
+		default:
+			fields[k] = fmt.Sprint(v)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,92 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		filter *tailf.Filter
+		line   string
+		want   bool
+	}{
+		{&tailf.Filter{}, "anything", true},
+		{&tailf.Filter{Include: []*regexp.Regexp{regexp.MustCompile("ERROR")}}, "level=ERROR boom", true},
+		{&tailf.Filter{Include: []*regexp.Regexp{regexp.MustCompile("ERROR")}}, "level=INFO ok", false},
+		{&tailf.Filter{Include: []*regexp.Regexp{regexp.MustCompile("ok$")}}, "level=INFO ok\r\n", true},
+		{&tailf.Filter{Exclude: []*regexp.Regexp{regexp.MustCompile("DEBUG")}}, "DEBUG noise", false},
+		{&tailf.Filter{Contains: []string{"he", "she", "hers"}}, "ushers", true},
+		{&tailf.Filter{Contains: []string{"abcd", "bce"}}, "xabcex", true},
+		{&tailf.Filter{Contains: []string{"abcd", "bcf"}}, "xabcex", false},
+		{&tailf.Filter{Contains: []string{"req-42"}}, "handled req-41", false},
+		{&tailf.Filter{
+			Fields: []tailf.FieldMatch{{Name: "status", Value: regexp.MustCompile("^5")}},
+		}, `msg="bad gateway" status=502`, true},
+		{&tailf.Filter{
+			Fields: []tailf.FieldMatch{{Name: "status", Value: regexp.MustCompile("^5")}},
+		}, `msg="all good" status=200`, false},
+		{&tailf.Filter{
+			Fields: []tailf.FieldMatch{{Name: "user"}},
+		}, `msg="no user here"`, false},
+		{&tailf.Filter{
+			Fields: []tailf.FieldMatch{{Name: "msg", Value: regexp.MustCompile("^timed out$")}},
+			Parse:  tailf.ParseJSON,
+		}, `{"msg":"timed out","code":7}`, true},
+	}
+
+	for i, tt := range tests {
+		if got := tt.filter.Match([]byte(tt.line)); got != tt.want {
+			t.Errorf("%d: Match(%q) = %v, want %v", i, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestFilterReader(t *testing.T) {
+	in := "INFO a\nERROR b\nINFO c\nERROR d"
+	filter := &tailf.Filter{Include: []*regexp.Regexp{regexp.MustCompile("ERROR")}}
+
+	got, err := ioutil.ReadAll(tailf.NewFilterReader(strings.NewReader(in), filter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ERROR b\nERROR d"; string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestFollowWithFilter(t *testing.T) {
+	withTempFile(t, time.Millisecond*500, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Filter:    &tailf.Filter{Contains: []string{"req=42"}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		_, err = file.WriteString("req=41 a\nreq=42 b\nreq=43 c\nreq=42 d\n")
+		if err != nil {
+			return err
+		}
+
+		want := "req=42 b\nreq=42 d\n"
+		data := make([]byte, len(want))
+		if _, err := io.ReadFull(follow, data); err != nil {
+			return err
+		}
+		if string(data) != want {
+			t.Errorf("want %q, got %q", want, data)
+		}
+		return nil
+	})
+}
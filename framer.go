@@ -0,0 +1,102 @@
+package tailf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrFrameTooLarge is returned by a LineReader when a frame doesn't fit
+// in its buffer, and by the framers reading a length larger than that.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// maxFrameSize is the largest frame a LineReader can buffer.
+const maxFrameSize = maxLineSize
+
+// Framer splits a stream of records, like a bufio.SplitFunc. It returns
+// how many bytes of data the first record takes, framing included, and
+// the record itself, or 0 if data doesn't hold a whole record yet.
+type Framer func(data []byte) (advance int, record []byte, err error)
+
+// DelimitedFramer splits records ending with delim, which is kept in
+// the records.
+func DelimitedFramer(delim []byte) Framer {
+	return func(data []byte) (int, []byte, error) {
+		i := bytes.Index(data, delim)
+		if i < 0 {
+			return 0, nil, nil
+		}
+		n := i + len(delim)
+		return n, data[:n], nil
+	}
+}
+
+// LengthPrefixFramer splits records prefixed with their length, an
+// unsigned integer of width bytes in the given byte order. Width must
+// be 1, 2, 4 or 8.
+func LengthPrefixFramer(width int, order binary.ByteOrder) Framer {
+	var length func([]byte) uint64
+	switch width {
+	case 1:
+		length = func(b []byte) uint64 { return uint64(b[0]) }
+	case 2:
+		length = func(b []byte) uint64 { return uint64(order.Uint16(b)) }
+	case 4:
+		length = func(b []byte) uint64 { return uint64(order.Uint32(b)) }
+	case 8:
+		length = order.Uint64
+	default:
+		panic("tailf: length prefix width must be 1, 2, 4 or 8")
+	}
+	return func(data []byte) (int, []byte, error) {
+		if len(data) < width {
+			return 0, nil, nil
+		}
+		return frameAfter(data, width, length(data))
+	}
+}
+
+// VarintFramer splits records prefixed with their length, an unsigned
+// varint as written by binary.PutUvarint.
+func VarintFramer() Framer {
+	return func(data []byte) (int, []byte, error) {
+		n, width := binary.Uvarint(data)
+		switch {
+		case width == 0:
+			return 0, nil, nil
+		case width < 0:
+			return 0, nil, ErrFrameTooLarge
+		}
+		return frameAfter(data, width, n)
+	}
+}
+
+// frameAfter returns the record of length n following a prefix of
+// width bytes.
+func frameAfter(data []byte, width int, n uint64) (int, []byte, error) {
+	if n > maxFrameSize-uint64(width) {
+		return 0, nil, ErrFrameTooLarge
+	}
+	end := width + int(n)
+	if len(data) < end {
+		return 0, nil, nil
+	}
+	return end, data[width:end], nil
+}
+
+// FixedSizeFramer splits records of size bytes. Size must be at least
+// 1.
+func FixedSizeFramer(size int) Framer {
+	if size < 1 {
+		panic("tailf: fixed frame size must be at least 1")
+	}
+	return func(data []byte) (int, []byte, error) {
+		if size > maxFrameSize {
+			return 0, nil, ErrFrameTooLarge
+		}
+		if len(data) < size {
+			return 0, nil, nil
+		}
+		return size, data[:size], nil
+	}
+}
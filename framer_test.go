@@ -0,0 +1,130 @@
+package tailf_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// chunked reads one byte at a time, to split the frames across reads.
+type chunked struct{ r io.Reader }
+
+func (c chunked) Read(b []byte) (int, error) {
+	if len(b) > 1 {
+		b = b[:1]
+	}
+	return c.r.Read(b)
+}
+
+func TestFramers(t *testing.T) {
+	varint := func(records ...string) []byte {
+		var out []byte
+		prefix := make([]byte, binary.MaxVarintLen64)
+		for _, r := range records {
+			n := binary.PutUvarint(prefix, uint64(len(r)))
+			out = append(append(out, prefix[:n]...), r...)
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		framer tailf.Framer
+		data   []byte
+		want   []string
+	}{
+		{"delimited", tailf.DelimitedFramer([]byte("\r\n")), []byte("a\r\nbc\r\nd"), []string{"a\r\n", "bc\r\n"}},
+		{"fixed", tailf.FixedSizeFramer(3), []byte("abcdefgh"), []string{"abc", "def"}},
+		{"prefix 1", tailf.LengthPrefixFramer(1, binary.BigEndian), []byte("\x01a\x02bc\x03d"), []string{"a", "bc"}},
+		{"prefix 2 big endian", tailf.LengthPrefixFramer(2, binary.BigEndian), []byte("\x00\x02ab\x00\x00\x00\x05a"), []string{"ab", ""}},
+		{"prefix 4 little endian", tailf.LengthPrefixFramer(4, binary.LittleEndian), []byte("\x03\x00\x00\x00abc\x01\x00"), []string{"abc"}},
+		{"prefix 8", tailf.LengthPrefixFramer(8, binary.BigEndian), []byte("\x00\x00\x00\x00\x00\x00\x00\x01a"), []string{"a"}},
+		{"varint", tailf.VarintFramer(), append(varint("a", string(make([]byte, 200))), 0x80), []string{"a", string(make([]byte, 200))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := tailf.NewFrameReader(chunked{bytes.NewReader(tt.data)}, tt.framer)
+			var got []string
+			for {
+				rec, err := frames.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, string(rec.Data))
+			}
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	frames := tailf.NewFrameReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), tailf.LengthPrefixFramer(4, binary.BigEndian))
+	if _, err := frames.Next(); !errors.Is(err, tailf.ErrFrameTooLarge) {
+		t.Errorf("want %v, got %v", tailf.ErrFrameTooLarge, err)
+	}
+}
+
+func TestFixedSizeFramerRejectsEmptyFrames(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want a frame size of %d to panic", size)
+				}
+			}()
+			tailf.FixedSizeFramer(size)
+		}()
+	}
+}
+
+func TestFollowFramesAcrossTruncation(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		// a whole frame, and the start of another
+		if _, err := file.Write([]byte("\x00\x03abc\x00\x05de")); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Framer:    tailf.LengthPrefixFramer(2, binary.BigEndian),
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		got := make([]byte, 3)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "abc" {
+			t.Errorf("want %q, got %q", "abc", got)
+		}
+
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte("\x00\x02xy"), 0); err != nil {
+			return err
+		}
+
+		got = make([]byte, 2)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "xy" {
+			t.Errorf("want the partial frame dropped and %q, got %q", "xy", got)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,128 @@
+package tailf
+
+import (
+	"sync"
+	"time"
+)
+
+// maxGrowthSamples bounds how many growths of the file are remembered
+// to date the unread data.
+const maxGrowthSamples = 64
+
+// Lag is how far behind the end of its file a follower is.
+type Lag struct {
+	// Bytes of the file not read yet.
+	Bytes int64
+	// Age of the oldest unread byte, as told by the modification
+	// times of the file. Zero when there's nothing to read.
+	Age time.Duration
+}
+
+// exceeds tells if the lag is past a limit. Zero fields of the limit
+// are ignored.
+func (l Lag) exceeds(limit Lag) bool {
+	return (limit.Bytes > 0 && l.Bytes > limit.Bytes) ||
+		(limit.Age > 0 && l.Age > limit.Age)
+}
+
+// growth is a size the file was seen growing to, and when.
+type growth struct {
+	size  int64
+	mtime time.Time
+}
+
+// lagTracker remembers how the file grew, to tell how old the unread
+// data is.
+type lagTracker struct {
+	mu       sync.Mutex
+	growths  []growth
+	exceeded bool
+}
+
+// grew records the file was seen at a size, as of its mtime.
+func (t *lagTracker) grew(size int64, mtime time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n := len(t.growths); n != 0 && t.growths[n-1].size >= size {
+		return
+	}
+	if len(t.growths) == maxGrowthSamples {
+		// forget about an intermediate growth, the oldest one is
+		// what dates the unread data
+		copy(t.growths[1:], t.growths[2:])
+		t.growths = t.growths[:len(t.growths)-1]
+	}
+	t.growths = append(t.growths, growth{size: size, mtime: mtime})
+}
+
+// reset forgets about the growths of a previous file.
+func (t *lagTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.growths = t.growths[:0]
+}
+
+// age returns how long the byte at offset has been waiting to be read.
+func (t *lagTracker) age(offset int64, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.growths) != 0 && t.growths[0].size <= offset {
+		t.growths = t.growths[1:]
+	}
+	if len(t.growths) == 0 {
+		return 0
+	}
+	if age := now.Sub(t.growths[0].mtime); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// Lag returns how far behind the end of its file the follower is.
+func (f *Follower) Lag() Lag {
+	f.mu.Lock()
+	fi, err := f.file.Stat()
+	offset := f.pos.offset
+	pending := f.pendingRotated()
+	f.mu.Unlock()
+	if err != nil {
+		return Lag{Bytes: pending}
+	}
+
+	f.lag.grew(fi.Size(), fi.ModTime())
+	lag := Lag{Bytes: fi.Size() - offset + pending}
+	if lag.Bytes < 0 {
+		// truncated, and we don't know it yet
+		lag.Bytes = 0
+	}
+	if lag.Bytes != 0 {
+		lag.Age = f.lag.age(offset, time.Now())
+	}
+	return lag
+}
+
+// caughtUp notes the follower has nothing left to read.
+func (f *Follower) caughtUp() {
+	f.lag.mu.Lock()
+	f.lag.exceeded = false
+	f.lag.mu.Unlock()
+}
+
+// checkLag calls the OnLag callback when the lag goes past the limit.
+// It's called again only once the lag went back under the limit.
+func (f *Follower) checkLag() {
+	if f.opts.OnLag == nil {
+		return
+	}
+	lag := f.Lag()
+	exceeds := lag.exceeds(f.opts.LagLimit)
+
+	f.lag.mu.Lock()
+	fire := exceeds && !f.lag.exceeded
+	f.lag.exceeded = exceeds
+	f.lag.mu.Unlock()
+
+	if fire {
+		f.opts.OnLag(lag)
+	}
+}
@@ -0,0 +1,74 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestLagTellsUnreadBytesAndTheirAge(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("0123456789"); err != nil {
+			return err
+		}
+		hourAgo := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(filename, hourAgo, hourAgo); err != nil {
+			return err
+		}
+
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		lag := follow.Lag()
+		if lag.Bytes != 10 || lag.Age < time.Hour {
+			t.Errorf("want 10 bytes lagging an hour, got %+v", lag)
+		}
+
+		if _, err := io.ReadFull(follow, make([]byte, 10)); err != nil {
+			return err
+		}
+		if lag := follow.Lag(); lag.Bytes != 0 || lag.Age != 0 {
+			t.Errorf("want no lag, got %+v", lag)
+		}
+		return nil
+	})
+}
+
+func TestOnLagFiresPastTheLimit(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		lagc := make(chan tailf.Lag, 10)
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			OnLag:    func(lag tailf.Lag) { lagc <- lag },
+			LagLimit: tailf.Lag{Bytes: 5},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if _, err := file.WriteString("0123456789"); err != nil {
+			return err
+		}
+		lag := <-lagc
+		if lag.Bytes != 10 {
+			t.Errorf("want 10 bytes of lag, got %+v", lag)
+		}
+
+		if _, err := file.WriteString("0123456789"); err != nil {
+			return err
+		}
+		select {
+		case lag := <-lagc:
+			t.Errorf("want a single call until caught up, got another with %+v", lag)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil
+	})
+}
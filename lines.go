@@ -0,0 +1,326 @@
+package tailf
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// maxLineSize is the longest line a LineReader will buffer. Longer
+// lines are handed out in chunks of that size.
+const maxLineSize = 1 << 20
+
+// Record is a line, or a frame, read from a followed file.
+type Record struct {
+	// Data holds the line, including its trailing newline if it
+	// had one, transcoded to UTF-8 if the file has an Encoding, or
+	// the record of the frame.
+	Data []byte
+	// Offset is the position of the line in the stream, counted
+	// from the first byte the reader returned, before transcoding.
+	Offset int64
+
+	// Generation and FileOffset locate the line when it comes from
+	// a Follower: Generation counts the files the follower opened
+	// before the one holding the line, because of rotations or
+	// truncations, and FileOffset is where the line begins in it.
+	// Dev and Ino identify that file, which can still be found once
+	// rotated, like for a Checkpoint.
+	Generation uint64
+	FileOffset int64
+	Dev        uint64
+	Ino        uint64
+
+	// Repeated, when not zero, tells the record stands for that many
+	// repeats of the line before it, collapsed by a Dedup.
+	Repeated int
+
+	ack *ack
+	// seeks counts the times the follower seeked before the record
+	// was read.
+	seeks int32
+}
+
+// Line returns the content of the record without its line ending.
+func (r *Record) Line() []byte {
+	return trimEOL(r.Data)
+}
+
+// Ack acknowledges the record was processed. Once all the records
+// before it were acknowledged too, the checkpoint of the follower moves
+// past it. It's a no-op for records that don't come from a follower
+// keeping a checkpoint.
+func (r *Record) Ack() error {
+	if r.ack == nil {
+		return nil
+	}
+	return r.ack.ack()
+}
+
+// RecordReader is implemented by the readers handing out Records,
+// like LineReader and Subscription.
+type RecordReader interface {
+	Next() (*Record, error)
+}
+
+// LineReader splits the stream of a follower in Records, one per
+// line, or one per frame when it has a Framer. Unlike a bufio.Scanner,
+// it keeps waiting when the follower wakes up without data to give.
+type LineReader struct {
+	r      io.Reader
+	framer Framer
+	buf    []byte
+	start  int
+	end    int
+	offset int64
+	err    error
+
+	// when reading from a follower, pos is where buf[start] is in
+	// the file. If the bytes after boundary come from another file,
+	// they start at next.
+	follower *Follower
+	pos      position
+	boundary int
+	next     position
+
+	// enc is the encoding of the files, and decoding the one of the
+	// current file, told by its byte order mark if it has one.
+	enc      Encoding
+	decoding Encoding
+	detected bool
+}
+
+// NewLineReader returns a LineReader reading from r.
+func NewLineReader(r io.Reader) *LineReader {
+	return &LineReader{r: r, buf: make([]byte, 4096)}
+}
+
+// NewFrameReader returns a LineReader handing out the frames of r, as
+// split by framer.
+func NewFrameReader(r io.Reader, framer Framer) *LineReader {
+	return &LineReader{r: r, framer: framer, buf: make([]byte, 4096)}
+}
+
+func newFollowerLineReader(f *Follower) *LineReader {
+	return &LineReader{buf: make([]byte, imin(imax(4096, f.opts.BufferSize), maxLineSize)), framer: f.opts.Framer, enc: f.opts.Encoding, follower: f, pos: f.pos, boundary: -1}
+}
+
+// Next returns the next line of the stream. Once the stream ends, the
+// last line is returned even if it isn't terminated, followed by the
+// error that ended the stream.
+//
+// With a Framer, Next returns the next frame instead, and never a
+// partial one: what's left of a frame at the end of the stream, or at
+// the end of a file the follower moved away from, is dropped.
+func (l *LineReader) Next() (*Record, error) {
+	l.seeked()
+	if l.framer != nil {
+		return l.nextFrame()
+	}
+	for {
+		end := l.end
+		if l.follower != nil && l.boundary >= 0 {
+			end = l.boundary
+		}
+		if l.enc != Raw && !l.detected {
+			if more := l.detect(end); more && end == l.end && l.err == nil {
+				l.fill()
+				continue
+			}
+		}
+		if n := l.decoding.newline(l.buf[l.start:end]); n >= 0 {
+			return l.take(n), nil
+		}
+		if end != l.end {
+			// the previous file ended without a newline
+			rec := l.take(end - l.start)
+			l.pos, l.boundary, l.detected = l.next, -1, false
+			return rec, nil
+		}
+		if l.err != nil {
+			if l.start < l.end {
+				return l.take(l.end - l.start), nil
+			}
+			return nil, l.err
+		}
+		if l.start == 0 && l.end == len(l.buf) && len(l.buf) >= maxLineSize {
+			// the line is too long, hand out what we have
+			return l.take(l.end), nil
+		}
+		l.fill()
+	}
+}
+
+func (l *LineReader) nextFrame() (*Record, error) {
+	for {
+		end := l.end
+		if l.follower != nil && l.boundary >= 0 {
+			end = l.boundary
+		}
+		advance, data, err := l.framer(l.buf[l.start:end])
+		if err != nil {
+			return nil, err
+		}
+		if advance > 0 {
+			return l.takeData(advance, data), nil
+		}
+		if end != l.end {
+			// the previous file ended in the middle of a frame
+			l.skip(end - l.start)
+			l.pos, l.boundary = l.next, -1
+			continue
+		}
+		if l.err != nil {
+			l.skip(l.end - l.start)
+			return nil, l.err
+		}
+		if l.start == 0 && l.end == len(l.buf) && len(l.buf) >= maxLineSize {
+			return nil, ErrFrameTooLarge
+		}
+		l.fill()
+	}
+}
+
+func (l *LineReader) take(n int) *Record {
+	return l.takeData(n, l.decoding.decode(l.buf[l.start:l.start+n]))
+}
+
+// detect tells the encoding of the current file, from its byte order
+// mark when at its start, and skips the mark. It returns true if it
+// needs more bytes to tell.
+func (l *LineReader) detect(end int) bool {
+	l.decoding = l.enc
+	atStart := l.offset == 0
+	if l.follower != nil {
+		atStart = l.pos.offset == 0
+	}
+	if atStart {
+		enc, n, more := detectBOM(l.buf[l.start:end])
+		if more {
+			return true
+		}
+		if n > 0 {
+			l.decoding = enc
+			l.skip(n)
+		}
+	}
+	l.detected = true
+	return false
+}
+
+// takeData hands out the next n bytes of the buffer as a record holding
+// data.
+func (l *LineReader) takeData(n int, data []byte) *Record {
+	rec := &Record{
+		Data:   append([]byte(nil), data...),
+		Offset: l.offset,
+	}
+	l.start += n
+	l.offset += int64(n)
+
+	if l.follower != nil {
+		rec.Generation = l.pos.gen
+		rec.FileOffset = l.pos.offset
+		rec.Dev, rec.Ino = l.pos.id.Dev, l.pos.id.Ino
+		rec.seeks = l.pos.seeks
+		if l.follower.acks != nil {
+			rec.ack = l.follower.acks.track(l.pos.id, l.pos.offset, l.pos.offset+int64(n))
+		}
+		l.pos.offset += int64(n)
+	}
+	return rec
+}
+
+// skip drops the next n bytes of the buffer.
+func (l *LineReader) skip(n int) {
+	l.start += n
+	l.offset += int64(n)
+	l.pos.offset += int64(n)
+}
+
+func (l *LineReader) fill() {
+	if l.start > 0 {
+		l.end = copy(l.buf, l.buf[l.start:l.end])
+		l.start = 0
+	}
+	if l.end == len(l.buf) {
+		buf := make([]byte, imin(2*len(l.buf), maxLineSize))
+		copy(buf, l.buf[:l.end])
+		l.buf = buf
+	}
+	if l.follower == nil {
+		n, err := l.r.Read(l.buf[l.end:])
+		l.end += n
+		l.err = err
+		return
+	}
+
+	n, at, err := l.follower.read(l.buf[l.end:])
+	if n != 0 {
+		switch {
+		case at.seeks != l.pos.seeks:
+			// the follower seeked, what was buffered is stale
+			copy(l.buf, l.buf[l.end:l.end+n])
+			l.drop()
+			l.pos = at
+		case l.start == l.end:
+			if at.gen != l.pos.gen {
+				l.detected = false
+			}
+			l.pos = at
+		case at.gen != l.pos.gen:
+			l.boundary, l.next = l.end, at
+		}
+	}
+	l.end += n
+	l.err = err
+}
+
+func trimEOL(line []byte) []byte {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
+
+// LastLinesOffset returns the offset at which the last n lines of a file
+// begin, like `tail -n` would print them. It's meant to be used as the
+// Offset of a follower's Options.
+func LastLinesOffset(filename string, n int) (int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := fi.Size()
+	if n <= 0 {
+		return end, nil
+	}
+
+	buf := make([]byte, 4096)
+	pos := end
+	for pos > 0 {
+		chunk := int64(len(buf))
+		if pos < chunk {
+			chunk = pos
+		}
+		pos -= chunk
+		if _, err := file.ReadAt(buf[:chunk], pos); err != nil {
+			return 0, err
+		}
+		for i := chunk - 1; i >= 0; i-- {
+			if buf[i] != '\n' || pos+i == end-1 {
+				// the newline ending the last line doesn't count
+				continue
+			}
+			n--
+			if n == 0 {
+				return pos + i + 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
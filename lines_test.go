@@ -0,0 +1,55 @@
+package tailf_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestLineReaderOffsets(t *testing.T) {
+	lines := tailf.NewLineReader(strings.NewReader("a\nbb\r\nccc"))
+	want := []struct {
+		line   string
+		offset int64
+	}{{"a", 0}, {"bb", 2}, {"ccc", 6}}
+
+	for _, w := range want {
+		rec, err := lines.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rec.Line()) != w.line || rec.Offset != w.offset {
+			t.Errorf("want %q@%d, got %q@%d", w.line, w.offset, rec.Line(), rec.Offset)
+		}
+	}
+	if _, err := lines.Next(); err != io.EOF {
+		t.Errorf("want EOF, got %v", err)
+	}
+}
+
+func TestLastLinesOffset(t *testing.T) {
+	file, err := ioutil.TempFile(os.TempDir(), "tailf_test")
+	if err != nil {
+		t.Fatalf("couldn't create temp file: '%v'", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.WriteString("one\ntwo\nthree\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	for n, want := range []int64{14, 8, 4, 0, 0} {
+		got, err := tailf.LastLinesOffset(file.Name(), n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("last %d lines: want offset %d, got %d", n, want, got)
+		}
+	}
+}
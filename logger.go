@@ -0,0 +1,13 @@
+package tailf
+
+// Logger receives the diagnostics of a follower, as a message and
+// key-value pairs. A *slog.Logger can be used as is.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
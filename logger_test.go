@@ -0,0 +1,72 @@
+package tailf_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// syncBuffer is written to by the follower's goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLoggerTellsAboutReopens(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		out := &syncBuffer{}
+		logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{Logger: logger})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		file, err = os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if _, err := file.WriteString("hello"); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(follow, make([]byte, 5)); err != nil {
+			return err
+		}
+
+		logs := out.String()
+		for _, want := range []string{
+			`msg="tailf: file went away, waiting for a new one"`,
+			`msg="tailf: reopening file"`,
+		} {
+			if !strings.Contains(logs, want) {
+				t.Errorf("want %s in logs:\n%s", want, logs)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package tailf
+
+// Pause stops the follower reading its file until Resume is called: Read
+// and Next block once they handed out what was read before. The
+// follower still keeps up with the rotations and truncations of the
+// file, and resumes where it was: a file rotated meanwhile is kept open
+// and read to its end first.
+func (f *Follower) Pause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paused == nil {
+		f.paused = make(chan struct{})
+	}
+}
+
+// Resume has a paused follower read its file again.
+func (f *Follower) Resume() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paused != nil {
+		close(f.paused)
+		f.paused = nil
+	}
+}
+
+// waitResumed waits for the follower to be resumed if it's paused. It
+// returns false if the follower is closed first.
+func (f *Follower) waitResumed() bool {
+	f.mu.Lock()
+	paused := f.paused
+	f.mu.Unlock()
+	if paused == nil {
+		return true
+	}
+	select {
+	case <-paused:
+		return true
+	case <-f.done:
+		return false
+	}
+}
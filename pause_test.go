@@ -0,0 +1,67 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestPauseAcrossRotation(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("one\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		if _, err := io.ReadFull(follow, make([]byte, 4)); err != nil {
+			return err
+		}
+
+		follow.Pause()
+		if _, err := file.WriteString("two\n"); err != nil {
+			return err
+		}
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte("three\n"), 0600); err != nil {
+			return err
+		}
+		for follow.Stats().Rotations == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		// the rest of the rotated file is still to be read
+		if lag := follow.Lag().Bytes; lag != 10 {
+			t.Errorf("want 10 bytes left to read, got %d", lag)
+		}
+
+		read := make(chan error, 1)
+		got := make([]byte, 10)
+		go func() {
+			_, err := io.ReadFull(follow, got)
+			read <- err
+		}()
+		select {
+		case <-read:
+			return fmt.Errorf("read %q while paused", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		follow.Resume()
+		if err := <-read; err != nil {
+			return err
+		}
+		if string(got) != "two\nthree\n" {
+			t.Errorf("want to resume with %q, got %q", "two\nthree\n", got)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,146 @@
+package tailf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dirPollInterval is how often a follower looks for its directory to
+// come back.
+const dirPollInterval = time.Second
+
+// FilePolicy is what a follower does when its file is truncated or
+// removed.
+type FilePolicy int
+
+const (
+	// Continue carries on: from the start of a truncated file, or
+	// with the file replacing a removed one.
+	Continue FilePolicy = iota
+	// Fail ends the stream, Read returning an ErrFileTruncated or an
+	// ErrFileRemoved.
+	Fail
+	// Notify carries on like Continue, after calling the OnFileEvent
+	// callback with an ErrFileTruncated or an ErrFileRemoved.
+	Notify
+)
+
+// isFileChange tells if an error is about the file being truncated or
+// removed, which only the policies decide what to do about.
+func isFileChange(err error) bool {
+	return errors.Is(err, ErrTruncated) || errors.Is(err, ErrRemoved)
+}
+
+// applyPolicy returns the error to end the stream with, if the policy
+// says to.
+func (f *Follower) applyPolicy(policy FilePolicy, err error) error {
+	switch policy {
+	case Fail:
+		return err
+	case Notify:
+		if f.opts.OnFileEvent != nil {
+			f.opts.OnFileEvent(err)
+		}
+	}
+	return nil
+}
+
+// truncated applies the truncation policy, then reopens the file.
+func (f *Follower) truncated(err error) error {
+	if err := f.applyPolicy(f.opts.TruncatePolicy, err); err != nil {
+		return err
+	}
+	return f.reopenFile(true)
+}
+
+// removed applies the removal policy when the file is deleted, and
+// starts the removal timeout whenever it goes away.
+func (f *Follower) removed(deleted bool) error {
+	if deleted {
+		if err := f.applyPolicy(f.opts.RemovePolicy, f.removedError(nil)); err != nil {
+			return err
+		}
+	}
+	if f.opts.RemoveTimeout > 0 && f.removal == nil {
+		f.removal = time.NewTimer(f.opts.RemoveTimeout)
+	}
+	if _, err := os.Stat(filepath.Dir(f.filename)); os.IsNotExist(err) {
+		// the directory went along, and its own event only comes once
+		// nothing holds it, like the file still open
+		return f.waitForDir()
+	}
+	return nil
+}
+
+// recreated stops the removal timeout.
+func (f *Follower) recreated() {
+	if f.removal != nil {
+		f.removal.Stop()
+		f.removal = nil
+	}
+}
+
+// removalTimeout returns when the removal timeout expires, or nil if
+// it isn't running.
+func (f *Follower) removalTimeout() <-chan time.Time {
+	if f.removal == nil {
+		return nil
+	}
+	return f.removal.C
+}
+
+// checkRemoved ends the stream if the file still isn't back once the
+// removal timeout expired.
+func (f *Follower) checkRemoved() error {
+	f.removal = nil
+	_, err := os.Stat(f.filename)
+	if os.IsNotExist(err) {
+		f.opts.Logger.Warn("tailf: file wasn't recreated in time", "path", f.filename, "timeout", f.opts.RemoveTimeout)
+		return f.removedError(err)
+	}
+	return nil
+}
+
+func (f *Follower) removedError(cause error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return ErrFileRemoved{Path: f.filename, Inode: f.pos.id.Ino, Offset: f.pos.offset, Err: cause}
+}
+
+// waitForDir waits for the directory of the file to come back after it
+// was removed or renamed, then watches it again.
+func (f *Follower) waitForDir() error {
+	dir := filepath.Dir(f.filename)
+	f.opts.Logger.Warn("tailf: directory went away, waiting for it", "path", f.filename, "dir", dir)
+	if err := f.applyPolicy(f.opts.RemovePolicy, f.removedError(ErrDirRemoved)); err != nil {
+		return err
+	}
+
+	var timeout <-chan time.Time
+	if f.opts.RemoveTimeout > 0 {
+		t := time.NewTimer(f.opts.RemoveTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		select {
+		case <-time.After(dirPollInterval):
+		case <-timeout:
+			f.opts.Logger.Warn("tailf: directory wasn't recreated in time", "path", f.filename, "dir", dir, "timeout", f.opts.RemoveTimeout)
+			return f.removedError(ErrDirRemoved)
+		case <-f.done:
+			return nil
+		}
+	}
+
+	f.opts.Logger.Debug("tailf: directory is back", "path", f.filename, "dir", dir)
+	if err := f.rebuildWatch(); err != nil {
+		return err
+	}
+	return f.rescan()
+}
@@ -0,0 +1,321 @@
+package tailf_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// readUntilError reads the follower until it fails, and returns what was
+// read.
+func readUntilError(r io.Reader) (string, error) {
+	var got []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil {
+			return string(got), err
+		}
+	}
+}
+
+func TestTruncatePolicyFail(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("hello\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, TruncatePolicy: tailf.Fail})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		if _, err := io.ReadFull(follow, make([]byte, 6)); err != nil {
+			return err
+		}
+
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte("a"), 0); err != nil {
+			return err
+		}
+
+		_, err = readUntilError(follow)
+		var terr tailf.ErrFileTruncated
+		if !errors.As(err, &terr) {
+			return fmt.Errorf("want a truncation error, got %v", err)
+		}
+		if terr.Path != filename || terr.Offset != 6 {
+			t.Errorf("want the truncation of %s after 6 bytes, got %+v", filename, terr)
+		}
+		return nil
+	})
+}
+
+func TestTruncatePolicyNotify(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("hello\n"); err != nil {
+			return err
+		}
+		events := make(chan error, 1)
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart:      true,
+			TruncatePolicy: tailf.Notify,
+			OnFileEvent:    func(err error) { events <- err },
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		if _, err := io.ReadFull(follow, make([]byte, 6)); err != nil {
+			return err
+		}
+
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte("bye\n"), 0); err != nil {
+			return err
+		}
+
+		if err := <-events; !errors.Is(err, tailf.ErrTruncated) {
+			t.Errorf("want a truncation event, got %v", err)
+		}
+		got := make([]byte, 4)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "bye\n" {
+			t.Errorf("want to carry on with %q, got %q", "bye\n", got)
+		}
+		return nil
+	})
+}
+
+func TestRemovePolicyFail(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{RemovePolicy: tailf.Fail})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		if _, err := readUntilError(follow); !errors.Is(err, tailf.ErrRemoved) {
+			t.Errorf("want a removal error, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestFailsAfterDraining(t *testing.T) {
+	for name, opts := range map[string]tailf.Options{
+		"truncated": {TruncatePolicy: tailf.Fail},
+		"removed":   {RemovePolicy: tailf.Fail},
+	} {
+		t.Run(name, func(t *testing.T) {
+			withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+				follow, err := tailf.FollowWithOptions(filename, opts)
+				if err != nil {
+					return fmt.Errorf("failed creating tailf.follower: %v", err)
+				}
+				defer follow.Close()
+
+				if _, err := file.WriteString("last words\n"); err != nil {
+					return err
+				}
+				// let the write event fill the buffer
+				time.Sleep(50 * time.Millisecond)
+				if opts.TruncatePolicy == tailf.Fail {
+					err = file.Truncate(0)
+				} else {
+					err = os.Remove(filename)
+				}
+				if err != nil {
+					return err
+				}
+				// and the error be waiting for the reader
+				time.Sleep(50 * time.Millisecond)
+
+				got, err := readUntilError(follow)
+				if !errors.Is(err, tailf.ErrTruncated) && !errors.Is(err, tailf.ErrRemoved) {
+					t.Errorf("want the stream to fail, got %v", err)
+				}
+				if got != "last words\n" {
+					t.Errorf("want %q before the error, got %q", "last words\n", got)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestRemoveTimeout(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{RemoveTimeout: 50 * time.Millisecond})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		start := time.Now()
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+		_, err = readUntilError(follow)
+		var rerr tailf.ErrFileRemoved
+		if !errors.As(err, &rerr) || rerr.Path != filename {
+			t.Errorf("want the removal of %s, got %v", filename, err)
+		}
+		if time.Since(start) < 50*time.Millisecond {
+			t.Error("want the stream to end only after the timeout")
+		}
+		return nil
+	})
+}
+
+func TestRemoveTimeoutStopsOnRecreation(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{RemoveTimeout: 50 * time.Millisecond})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			return err
+		}
+		if err := restoreFile(filename, ""); err != nil {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := appendFile(filename, "still here\n"); err != nil {
+			return err
+		}
+
+		got := make([]byte, 11)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "still here\n" {
+			t.Errorf("want %q, got %q", "still here\n", got)
+		}
+		return nil
+	})
+}
+
+func appendFile(filename, content string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.WriteString(content)
+	return err
+}
+
+// withTempDir runs action on a file in a directory of its own, which it
+// can remove.
+func withTempDir(t *testing.T, timeout time.Duration, action func(t *testing.T, dir, filename string) error) {
+	root, err := ioutil.TempDir(os.TempDir(), "tailf_test_dir")
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: '%v'", err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "logs")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatalf("couldn't create dir: '%v'", err)
+	}
+	filename := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(filename, nil, 0600); err != nil {
+		t.Fatalf("couldn't create file: '%v'", err)
+	}
+
+	errc := make(chan error)
+	go func() { errc <- action(t, dir, filename) }()
+	select {
+	case err = <-errc:
+		if err != nil {
+			t.Errorf("failure: %v", err)
+		}
+	case <-time.After(timeout):
+		t.Error("test took too long :(")
+	}
+}
+
+func TestFollowsIntoRecreatedDir(t *testing.T) {
+	withTempDir(t, 5*time.Second, func(t *testing.T, dir, filename string) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		time.Sleep(50 * time.Millisecond)
+		if err := os.Mkdir(dir, 0700); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte("back\n"), 0600); err != nil {
+			return err
+		}
+
+		got := make([]byte, 5)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "back\n" {
+			t.Errorf("want %q, got %q", "back\n", got)
+		}
+		return nil
+	})
+}
+
+func TestRemovePolicyFailOnDirRename(t *testing.T) {
+	withTempDir(t, time.Second, func(t *testing.T, dir, filename string) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{RemovePolicy: tailf.Fail})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.Rename(dir, dir+".old"); err != nil {
+			return err
+		}
+		if _, err := readUntilError(follow); !errors.Is(err, tailf.ErrDirRemoved) {
+			t.Errorf("want the directory removal, got %v", err)
+		}
+		return nil
+	})
+}
+
+func TestRemoveTimeoutOnDirRename(t *testing.T) {
+	withTempDir(t, time.Second, func(t *testing.T, dir, filename string) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{RemoveTimeout: 50 * time.Millisecond})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := os.Rename(dir, dir+".old"); err != nil {
+			return err
+		}
+		_, err = readUntilError(follow)
+		if !errors.Is(err, tailf.ErrRemoved) || !errors.Is(err, tailf.ErrDirRemoved) {
+			t.Errorf("want the directory removal, got %v", err)
+		}
+		return nil
+	})
+}
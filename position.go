@@ -0,0 +1,48 @@
+package tailf
+
+import "sync/atomic"
+
+// Position is where a follower is in the files it follows.
+type Position struct {
+	// Path, Dev and Ino identify the file the next byte comes from.
+	// Path is the one of the followed file, even for the bytes left
+	// to read from it after it was rotated away, except for the
+	// rotated siblings a follower catches up with.
+	Path string
+	Dev  uint64
+	Ino  uint64
+	// Generation counts the files the follower moved on from before
+	// this one, because of rotations or truncations.
+	Generation uint64
+	// Offset is where the next byte is in the file.
+	Offset int64
+	// Delivered counts the bytes read so far, across all the files.
+	Delivered int64
+}
+
+// Position returns where the follower is in the files it follows. With
+// Next, or with options requiring Read to go through records, the
+// follower reads ahead of the records handed out by up to a buffer.
+func (f *Follower) Position() Position {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, pos := f.filename, f.pos
+	if len(f.rotations) != 0 {
+		// the previous files are drained first
+		pos = f.rotations[0].position
+	}
+	if f.catchup != nil {
+		if p, at, ok := f.catchup.current(); ok {
+			path, pos = p, at
+		}
+	}
+	return Position{
+		Path:       path,
+		Dev:        pos.id.Dev,
+		Ino:        pos.id.Ino,
+		Generation: pos.gen,
+		Offset:     pos.offset,
+		Delivered:  atomic.LoadInt64(&f.counters.bytesRead),
+	}
+}
@@ -0,0 +1,51 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestPosition(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("hello\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		if _, err := io.ReadFull(follow, make([]byte, 6)); err != nil {
+			return err
+		}
+
+		pos := follow.Position()
+		if pos.Path != filename || pos.Generation != 0 || pos.Offset != 6 || pos.Delivered != 6 {
+			t.Errorf("want %s at 6 of generation 0, got %+v", filename, pos)
+		}
+
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte("bye\n"), 0); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(follow, make([]byte, 4)); err != nil {
+			return err
+		}
+		before := pos
+		pos = follow.Position()
+		if pos.Dev != before.Dev || pos.Ino != before.Ino {
+			t.Errorf("want the same file once truncated, got inode %d then %d", before.Ino, pos.Ino)
+		}
+		if pos.Generation != 1 || pos.Offset != 4 || pos.Delivered != 10 {
+			t.Errorf("want 4 of generation 1 and 10 bytes delivered, got %+v", pos)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,192 @@
+package tailf
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit bounds how fast the lines of a followed file are handed out.
+// A line is let through when it fits under every limit that is set.
+type RateLimit struct {
+	BytesPerSecond int64
+	LinesPerSecond int64
+	// Drop drops the lines over the limit, counting them in the
+	// Dropped stat, instead of blocking the reader until they fit.
+	Drop bool
+}
+
+// Sampler keeps a deterministic share of the lines of a followed file.
+type Sampler struct {
+	// N keeps 1 line in N.
+	N int
+	// Field, if set, keeps the lines whose value of the field hashes
+	// to 1 in N, so that all the lines sharing a value are kept or
+	// dropped together. Lines without the field are sampled as if it
+	// were empty.
+	Field string
+	// Parse turns a line in fields. Defaults to ParseKeyValue.
+	Parse func(line []byte) map[string]string
+
+	seen uint64
+}
+
+// Keep tells if a line, with or without its line ending, is part of the
+// sample.
+func (s *Sampler) Keep(line []byte) bool {
+	if s.N <= 1 {
+		return true
+	}
+	if s.Field == "" {
+		return (atomic.AddUint64(&s.seen, 1)-1)%uint64(s.N) == 0
+	}
+	parse := s.Parse
+	if parse == nil {
+		parse = ParseKeyValue
+	}
+	h := fnv.New32a()
+	h.Write([]byte(parse(trimEOL(line))[s.Field]))
+	return h.Sum32()%uint32(s.N) == 0
+}
+
+// bucket is a token bucket refilled at rate tokens per second, holding
+// up to a second of them.
+type bucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate int64, now time.Time) *bucket {
+	return &bucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// wait returns how long until n tokens are available.
+func (b *bucket) wait(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// limitRecords hands out records no faster than the limit. The dropped
+// ones are acknowledged right away, there's nothing more to do with
+// them.
+type limitRecords struct {
+	src   RecordReader
+	limit *RateLimit
+	// dropped counts the dropped records, if not nil.
+	dropped func()
+	done    <-chan struct{}
+
+	bytes, lines *bucket
+}
+
+func newLimitRecords(src RecordReader, limit *RateLimit) *limitRecords {
+	lr := &limitRecords{src: src, limit: limit}
+	now := time.Now()
+	if limit.BytesPerSecond > 0 {
+		lr.bytes = newBucket(limit.BytesPerSecond, now)
+	}
+	if limit.LinesPerSecond > 0 {
+		lr.lines = newBucket(limit.LinesPerSecond, now)
+	}
+	return lr
+}
+
+func (lr *limitRecords) Next() (*Record, error) {
+	for {
+		rec, err := lr.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if lr.admit(float64(len(rec.Data))) {
+			return rec, nil
+		}
+		if lr.dropped != nil {
+			lr.dropped()
+		}
+		if err := rec.Ack(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// admit takes the tokens for a record of n bytes, blocking until they
+// are available unless the limit drops records.
+func (lr *limitRecords) admit(n float64) bool {
+	for {
+		now := time.Now()
+		var wait time.Duration
+		if lr.bytes != nil {
+			lr.bytes.refill(now)
+			// a line larger than the limit goes through once the
+			// bucket is full, rather than never
+			wait = lr.bytes.wait(minf(n, lr.bytes.rate))
+		}
+		if lr.lines != nil {
+			lr.lines.refill(now)
+			if w := lr.lines.wait(1); w > wait {
+				wait = w
+			}
+		}
+		if wait == 0 {
+			if lr.bytes != nil {
+				lr.bytes.tokens -= n
+			}
+			if lr.lines != nil {
+				lr.lines.tokens--
+			}
+			return true
+		}
+		if lr.limit.Drop {
+			return false
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-lr.done:
+			// let the source tell it's done
+			t.Stop()
+			return true
+		}
+	}
+}
+
+// sampleRecords only lets through the records of the sample. The
+// others are acknowledged right away.
+type sampleRecords struct {
+	src     RecordReader
+	sampler *Sampler
+}
+
+func (sr *sampleRecords) Next() (*Record, error) {
+	for {
+		rec, err := sr.src.Next()
+		if err != nil {
+			return nil, err
+		}
+		if sr.sampler.Keep(rec.Data) {
+			return rec, nil
+		}
+		if err := rec.Ack(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func minf(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
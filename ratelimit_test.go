@@ -0,0 +1,102 @@
+package tailf_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestSamplerKeepsOneInN(t *testing.T) {
+	s := &tailf.Sampler{N: 3}
+	var kept []int
+	for i := 0; i < 9; i++ {
+		if s.Keep([]byte("line\n")) {
+			kept = append(kept, i)
+		}
+	}
+	if fmt.Sprint(kept) != "[0 3 6]" {
+		t.Errorf("want lines [0 3 6] kept, got %v", kept)
+	}
+}
+
+func TestSamplerKeepsFieldValuesTogether(t *testing.T) {
+	s := &tailf.Sampler{N: 4, Field: "user"}
+	kept := 0
+	for i := 0; i < 100; i++ {
+		user := fmt.Sprintf("u%d", i)
+		keep := s.Keep([]byte("user=" + user + " msg=first"))
+		if s.Keep([]byte("msg=second user="+user)) != keep {
+			t.Fatalf("want the lines of %s kept or dropped together", user)
+		}
+		if keep {
+			kept++
+		}
+	}
+	if kept == 0 || kept == 100 {
+		t.Errorf("want a share of the users kept, got %d in 100", kept)
+	}
+}
+
+func TestRateLimitBlocks(t *testing.T) {
+	withTempFile(t, 2*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		line := strings.Repeat("x", 99) + "\n"
+		if _, err := file.WriteString(strings.Repeat(line, 15)); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Limit:     &tailf.RateLimit{BytesPerSecond: 1000},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		start := time.Now()
+		for i := 0; i < 15; i++ {
+			if _, err := follow.Next(); err != nil {
+				return err
+			}
+		}
+		// a second worth of lines goes right away, the rest at the rate
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("want the reader held back, read everything in %v", elapsed)
+		}
+		if dropped := follow.Stats().Dropped; dropped != 0 {
+			t.Errorf("want no line dropped, got %d", dropped)
+		}
+		return nil
+	})
+}
+
+func TestRateLimitDrops(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString(strings.Repeat("line\n", 10)); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Limit:     &tailf.RateLimit{LinesPerSecond: 3, Drop: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		for i := 0; i < 3; i++ {
+			if _, err := follow.Next(); err != nil {
+				return err
+			}
+		}
+		// the rest is dropped while waiting for more lines
+		go follow.Next()
+		for follow.Stats().Dropped != 7 {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+}
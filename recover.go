@@ -0,0 +1,166 @@
+package tailf
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// RecoveryPolicy is how a follower recovers from the errors of its
+// watch, instead of ending the stream on the first one.
+//
+// An overflow of the watch's event queue is recovered from by looking
+// at the file again. Other errors are recovered from by rebuilding the
+// watch after a backoff, then looking at the file again.
+type RecoveryPolicy struct {
+	// MaxRetries is how many errors in a row are recovered from
+	// before giving up. Zero means no limit.
+	MaxRetries int
+
+	// MinBackoff and MaxBackoff bound the wait before rebuilding the
+	// watch. Default to 100ms and 10s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// FallbackToPolling has the follower poll the file once it gave up
+	// on its watch, rather than ending the stream.
+	FallbackToPolling bool
+
+	// Permanent tells the errors not worth recovering from, which end
+	// the stream right away. Defaults to permission errors.
+	Permanent func(error) bool
+}
+
+func (p *RecoveryPolicy) permanent(err error) bool {
+	if p.Permanent != nil {
+		return p.Permanent(err)
+	}
+	return os.IsPermission(err)
+}
+
+// backoff returns the wait before the nth attempt to recover.
+func (p *RecoveryPolicy) backoff(attempt int) time.Duration {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max < min {
+		max = 10 * time.Second
+	}
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// recovery is what the follower does after an error.
+type recovery int
+
+const (
+	recovered recovery = iota
+	giveUp
+	poll
+)
+
+// recoverFrom tries to get the follower going again after its nth error
+// in a row, retrying for as long as the policy allows.
+func (f *Follower) recoverFrom(err error, failures int) recovery {
+	p := f.opts.Recovery
+	if p == nil || isFileChange(err) || p.permanent(err) {
+		return giveUp
+	}
+	if err == fsnotify.ErrEventOverflow {
+		f.opts.Logger.Warn("tailf: watch overflowed, rescanning the file", "path", f.filename)
+		if err := f.rescan(); err == nil {
+			return recovered
+		}
+	}
+	for {
+		if p.MaxRetries > 0 && failures > p.MaxRetries {
+			if p.FallbackToPolling {
+				return poll
+			}
+			return giveUp
+		}
+
+		backoff := p.backoff(failures)
+		f.opts.Logger.Warn("tailf: recovering from watch error", "path", f.filename, "err", err, "attempt", failures, "backoff", backoff)
+		if !f.sleep(backoff) {
+			return giveUp
+		}
+		err = f.rebuildWatch()
+		if err == nil {
+			err = f.rescan()
+		}
+		if err == nil {
+			f.opts.Logger.Debug("tailf: recovered from watch error", "path", f.filename)
+			return recovered
+		}
+		if isFileChange(err) || p.permanent(err) {
+			return giveUp
+		}
+		failures++
+	}
+}
+
+// rebuildWatch replaces the watch of the follower with a new one.
+func (f *Follower) rebuildWatch() error {
+	f.mu.Lock()
+	newWatcher := f.newWatcher
+	f.mu.Unlock()
+	watch, err := newWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watch.Add(filepath.Dir(f.filename)); err != nil {
+		// same as when following, settle for the file itself
+		if err := watch.Add(f.filename); err != nil {
+			_ = watch.Close()
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return watch.Close()
+	}
+	old := f.watch
+	f.watch = watch
+	return old.Close()
+}
+
+// rescan catches up with the changes to the file the watch may have
+// missed.
+func (f *Follower) rescan() error {
+	fi, err := os.Stat(f.filename)
+	if err != nil {
+		// wait for it to come back
+		return nil
+	}
+	f.mu.Lock()
+	current := f.pos.id
+	f.mu.Unlock()
+	if identify(fi) != current {
+		return f.reopenFile(false)
+	}
+	return f.handleFileEvent(fsnotify.Event{Name: f.filename, Op: fsnotify.Write})
+}
+
+// sleep waits for d, or returns false if the follower is closed.
+func (f *Follower) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-f.done:
+		return false
+	}
+}
@@ -0,0 +1,203 @@
+package tailf_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+	"gopkg.in/fsnotify.v1"
+)
+
+// breakFile replaces the file with a symlink to itself, which can't be
+// reopened.
+func breakFile(filename string) error {
+	if err := os.Rename(filename, filename+".1"); err != nil {
+		return err
+	}
+	return os.Symlink(filename, filename)
+}
+
+// restoreFile replaces the broken file with one holding content.
+func restoreFile(filename, content string) error {
+	if err := ioutil.WriteFile(filename+".new", []byte(content), 0600); err != nil {
+		return err
+	}
+	return os.Rename(filename+".new", filename)
+}
+
+func TestRecoversFromReopenFailure(t *testing.T) {
+	withTempFile(t, 2*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("one\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Recovery:  &tailf.RecoveryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		scan := bufio.NewScanner(follow)
+
+		if !scan.Scan() || scan.Text() != "one" {
+			return fmt.Errorf("want line %q, got %q (%v)", "one", scan.Text(), scan.Err())
+		}
+
+		if err := breakFile(filename); err != nil {
+			return err
+		}
+		for follow.Stats().ReopenFailures == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if err := restoreFile(filename, "two\n"); err != nil {
+			return err
+		}
+
+		if !scan.Scan() || scan.Text() != "two" {
+			return fmt.Errorf("want line %q, got %q (%v)", "two", scan.Text(), scan.Err())
+		}
+		return nil
+	})
+}
+
+func TestStopsOnErrorWithoutRecovery(t *testing.T) {
+	withTempFile(t, 2*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if err := breakFile(filename); err != nil {
+			return err
+		}
+		_, err = follow.Read(make([]byte, 10))
+		for err == nil {
+			_, err = follow.Read(make([]byte, 10))
+		}
+		if os.IsNotExist(err) {
+			t.Errorf("want the reopen error, got %v", err)
+		}
+		return nil
+	})
+}
+
+// waitForLog waits for the follower to log msg.
+func waitForLog(out *syncBuffer, msg string) {
+	for !strings.Contains(out.String(), msg) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRescansOnOverflow(t *testing.T) {
+	withTempFile(t, 2*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		out := &syncBuffer{}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Recovery:  &tailf.RecoveryPolicy{MaxRetries: 1},
+			Logger:    slog.New(slog.NewTextHandler(out, nil)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		scan := bufio.NewScanner(follow)
+
+		if _, err := file.WriteString("one\n"); err != nil {
+			return err
+		}
+		if !scan.Scan() || scan.Text() != "one" {
+			return fmt.Errorf("want line %q, got %q (%v)", "one", scan.Text(), scan.Err())
+		}
+
+		tailf.InjectWatchError(follow, fsnotify.ErrEventOverflow)
+		waitForLog(out, "watch overflowed")
+		scanned := make(chan bool)
+		go func() { scanned <- scan.Scan() }()
+		time.Sleep(10 * time.Millisecond)
+		if _, err := file.WriteString("two\n"); err != nil {
+			return err
+		}
+		if !<-scanned || scan.Text() != "two" {
+			return fmt.Errorf("want line %q, got %q (%v)", "two", scan.Text(), scan.Err())
+		}
+		return nil
+	})
+}
+
+func TestRecoveryForgetsPastErrors(t *testing.T) {
+	withTempFile(t, 2*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		out := &syncBuffer{}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Recovery:  &tailf.RecoveryPolicy{MaxRetries: 1, MinBackoff: time.Millisecond},
+			Logger:    slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		scan := bufio.NewScanner(follow)
+
+		// errors recovered from one at a time never add up
+		for i := 0; i < 3; i++ {
+			tailf.InjectWatchError(follow, errors.New("watch broke"))
+			for strings.Count(out.String(), "recovered from watch error") <= i {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		if _, err := file.WriteString("still here\n"); err != nil {
+			return err
+		}
+		if !scan.Scan() || scan.Text() != "still here" {
+			return fmt.Errorf("want line %q, got %q (%v)", "still here", scan.Text(), scan.Err())
+		}
+		return nil
+	})
+}
+
+func TestFallsBackToPolling(t *testing.T) {
+	withTempFile(t, 3*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			FromStart: true,
+			Recovery: &tailf.RecoveryPolicy{
+				MaxRetries:        1,
+				MinBackoff:        time.Millisecond,
+				FallbackToPolling: true,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		scan := bufio.NewScanner(follow)
+
+		// the watch can't be rebuilt, the retry is one too many
+		tailf.FailWatchRebuilds(follow, errors.New("no more inotify instances"))
+		tailf.InjectWatchError(follow, errors.New("watch broke"))
+		for !follow.Stats().Polling {
+			time.Sleep(time.Millisecond)
+		}
+
+		// the reader waits for the poll to notice the write
+		scanned := make(chan bool)
+		go func() { scanned <- scan.Scan() }()
+		time.Sleep(10 * time.Millisecond)
+		if _, err := file.WriteString("polled\n"); err != nil {
+			return err
+		}
+		if !<-scanned || scan.Text() != "polled" {
+			return fmt.Errorf("want line %q, got %q (%v)", "polled", scan.Text(), scan.Err())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package tailf
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// ReadAt reads from the file the follower is currently reading, at an
+// offset, like to show the lines around one of interest. It doesn't
+// move the follower.
+func (f *Follower) ReadAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.ReadAt(b, off)
+}
+
+// Seek moves the follower in the file it's currently reading, relative
+// to how far it read it for io.SeekCurrent. What was read of the file
+// or of the previous ones but not handed out yet is dropped, except for
+// the line a Dedup might be holding: the lines it read ahead are
+// dropped too. It must not be called while a Read or a Next is in
+// progress.
+func (f *Follower) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos.offset
+	case io.SeekEnd:
+		fi, err := f.file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += fi.Size()
+	default:
+		return 0, errors.New("tailf: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("tailf: negative position")
+	}
+	if _, err := f.file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	// drop the buffered bytes, of this file and the previous ones
+	f.fileReader.Reset(f.file)
+	f.behind = true
+	f.dropRotations()
+	if f.catchup != nil && f.catchup.r != nil {
+		_ = f.catchup.r.Close()
+	}
+	f.catchup = nil
+	f.pos.offset = offset
+	f.pos.seeks++
+	if rb, ok := f.out.(*recordBytes); ok {
+		rb.pending = nil
+	}
+	// and have the line reader drop its own
+	atomic.StoreInt32(&f.seeks, f.pos.seeks)
+	return offset, nil
+}
+
+// seeked drops the buffered bytes if the follower seeked since they
+// were read.
+func (l *LineReader) seeked() {
+	if l.follower != nil && atomic.LoadInt32(&l.follower.seeks) != l.pos.seeks {
+		l.drop()
+	}
+}
+
+func (l *LineReader) drop() {
+	l.start, l.end, l.boundary = 0, 0, -1
+	l.detected = false
+}
@@ -0,0 +1,121 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestReadAt(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("one\ntwo\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		got := make([]byte, 3)
+		if _, err := follow.ReadAt(got, 4); err != nil {
+			return err
+		}
+		if string(got) != "two" {
+			t.Errorf("want %q, got %q", "two", got)
+		}
+
+		// the follower didn't move
+		if _, err := file.WriteString("three\n"); err != nil {
+			return err
+		}
+		got = make([]byte, 6)
+		if _, err := io.ReadFull(follow, got); err != nil {
+			return err
+		}
+		if string(got) != "three\n" {
+			t.Errorf("want %q, got %q", "three\n", got)
+		}
+		return nil
+	})
+}
+
+func TestSeek(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("one\ntwo\nthree\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Data) != "one\n" {
+			t.Errorf("want %q, got %q", "one\n", rec.Data)
+		}
+
+		// the rest of the file was read already, and is dropped
+		if at, err := follow.Seek(-6, io.SeekEnd); err != nil || at != 8 {
+			return fmt.Errorf("want to seek to 8, got %d, %v", at, err)
+		}
+		rec, err = follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Data) != "three\n" || rec.FileOffset != 8 {
+			t.Errorf("want %q@8, got %q@%d", "three\n", rec.Data, rec.FileOffset)
+		}
+
+		if _, err := follow.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		rec, err = follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Data) != "one\n" || rec.FileOffset != 0 {
+			t.Errorf("want %q@0, got %q@%d", "one\n", rec.Data, rec.FileOffset)
+		}
+		return nil
+	})
+}
+
+func TestSeekWithDedup(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString("one\ntwo\nthree\n"); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, Dedup: &tailf.Dedup{}})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		if rec, err := follow.Next(); err != nil || string(rec.Data) != "one\n" {
+			return fmt.Errorf("want %q, got %v (%v)", "one\n", rec, err)
+		}
+		// let the dedup read ahead
+		time.Sleep(10 * time.Millisecond)
+
+		if _, err := follow.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Data) != "one\n" || rec.FileOffset != 0 || rec.Repeated != 0 {
+			t.Errorf("want %q@0, got %q@%d repeated %d times", "one\n", rec.Data, rec.FileOffset, rec.Repeated)
+		}
+		return nil
+	})
+}
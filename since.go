@@ -0,0 +1,174 @@
+package tailf
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// timestampLayouts are the layouts ParseTimestamp recognizes at the
+// start of a line. The ones without a zone are in local time.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ParseTimestamp returns the time a line was logged at, as found at the
+// start of the line, or in a ts, time or timestamp field when the line
+// is made of key=value pairs. Timestamps without a zone are taken to be
+// in local time.
+func ParseTimestamp(line []byte) (time.Time, bool) {
+	line = trimEOL(line)
+	first := line
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		first = line[:i]
+		// a date and a time separated by a space
+		second := line[i+1:]
+		if j := bytes.IndexByte(second, ' '); j >= 0 {
+			second = second[:j]
+		}
+		if t, ok := parseLayouts(string(first) + " " + string(second)); ok {
+			return t, true
+		}
+	}
+	if t, ok := parseLayouts(string(first)); ok {
+		return t, true
+	}
+	fields := ParseKeyValue(line)
+	for _, name := range []string{"ts", "time", "timestamp"} {
+		if v, ok := fields[name]; ok {
+			if t, ok := parseLayouts(v); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseLayouts(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SinceOffset returns the offset of the first line of a file logged at
+// or after since, as told by timestamp, or the size of the file if
+// there's none. The lines are assumed to be in chronological order,
+// which lets the file be binary searched instead of read. Lines without
+// a timestamp go along with the line before them. A nil timestamp
+// defaults to ParseTimestamp.
+func SinceOffset(filename string, since time.Time, timestamp func(line []byte) (time.Time, bool)) (int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return sinceOffset(file, fi.Size(), since, timestamp)
+}
+
+func sinceOffset(file io.ReaderAt, size int64, since time.Time, timestamp func([]byte) (time.Time, bool)) (int64, error) {
+	if timestamp == nil {
+		timestamp = ParseTimestamp
+	}
+	// lo is always the start of a line
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, end, t, ok, err := nextStamped(file, size, mid, hi, timestamp)
+		switch {
+		case err != nil:
+			return 0, err
+		case !ok:
+			// only lines going along with an earlier one, look for
+			// the first line with a timestamp from lo instead
+			start, end, t, ok, err = nextStamped(file, size, lo, hi, timestamp)
+			switch {
+			case err != nil:
+				return 0, err
+			case !ok:
+				lo = hi
+			case t.Before(since):
+				lo = end
+			default:
+				lo, hi = start, start
+			}
+		case t.Before(since):
+			lo = end
+		default:
+			hi = start
+		}
+	}
+	return lo, nil
+}
+
+// nextStamped returns the first line with a timestamp starting at or
+// after from, and before limit.
+func nextStamped(file io.ReaderAt, size, from, limit int64, timestamp func([]byte) (time.Time, bool)) (start, end int64, t time.Time, ok bool, err error) {
+	start = from
+	if from > 0 {
+		// the line starts after the end of the one holding from-1
+		start = from - 1
+	}
+	r := bufio.NewReader(io.NewSectionReader(file, start, size-start))
+	if from > 0 {
+		n, err := skipLine(r)
+		start += n
+		if err != nil {
+			return 0, 0, time.Time{}, false, ignoreEOF(err)
+		}
+	}
+
+	for start < limit {
+		line, err := r.ReadSlice('\n')
+		var stamped bool
+		if len(line) != 0 {
+			t, stamped = timestamp(line)
+		}
+		n := int64(len(line))
+		if err == bufio.ErrBufferFull {
+			// too long to be held, the timestamp is at its start
+			var rest int64
+			rest, err = skipLine(r)
+			n += rest
+		}
+		if stamped {
+			return start, start + n, t, true, nil
+		}
+		if err != nil {
+			return 0, 0, time.Time{}, false, ignoreEOF(err)
+		}
+		start += n
+	}
+	return 0, 0, time.Time{}, false, nil
+}
+
+// skipLine reads up to the end of the current line, and returns how
+// many bytes that was.
+func skipLine(r *bufio.Reader) (int64, error) {
+	var n int64
+	for {
+		line, err := r.ReadSlice('\n')
+		n += int64(len(line))
+		if err != bufio.ErrBufferFull {
+			return n, err
+		}
+	}
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
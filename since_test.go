@@ -0,0 +1,106 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	// away from UTC, for the timestamps without a zone to tell
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.FixedZone("UTC+2", 2*60*60)
+
+	utc := time.Date(2024, 3, 1, 10, 42, 0, 0, time.UTC)
+	local := time.Date(2024, 3, 1, 10, 42, 0, 0, time.Local)
+	for line, want := range map[string]time.Time{
+		"2024-03-01T10:42:00Z GET /\n":                    utc,
+		"2024-03-01 10:42:00 GET /":                       local,
+		"2024-03-01T10:42:00.123 GET /":                   local.Add(123 * time.Millisecond),
+		`level=info ts=2024-03-01T10:42:00Z msg="GET /"`:  utc,
+		`level=info ts="2024-03-01 10:42:00" msg="GET /"`: local,
+	} {
+		got, ok := tailf.ParseTimestamp([]byte(line))
+		if !ok || !got.Equal(want) {
+			t.Errorf("want %v in %q, got %v (%v)", want, line, got, ok)
+		}
+	}
+	if _, ok := tailf.ParseTimestamp([]byte("\tat main.main()")); ok {
+		t.Error("want no timestamp in a line without one")
+	}
+}
+
+func TestSinceOffset(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	var content strings.Builder
+	var offsets []int64
+	for i := 0; i < 100; i++ {
+		offsets = append(offsets, int64(content.Len()))
+		fmt.Fprintf(&content, "%s line %d\n", base.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i)
+		if i%7 == 0 {
+			// a stack trace going along with the line
+			content.WriteString("\tat main.main()\n\tat runtime.main()\n")
+		}
+	}
+
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		if _, err := file.WriteString(content.String()); err != nil {
+			return err
+		}
+		for _, i := range []int{0, 1, 7, 8, 42, 99} {
+			got, err := tailf.SinceOffset(filename, base.Add(time.Duration(i)*time.Minute), nil)
+			if err != nil {
+				return err
+			}
+			if got != offsets[i] {
+				t.Errorf("want line %d at %d, got %d", i, offsets[i], got)
+			}
+		}
+		// between two lines, and after the last one
+		got, err := tailf.SinceOffset(filename, base.Add(42*time.Minute+time.Second), nil)
+		if err != nil {
+			return err
+		}
+		if got != offsets[43] {
+			t.Errorf("want line 43 at %d, got %d", offsets[43], got)
+		}
+		got, err = tailf.SinceOffset(filename, base.Add(time.Hour*2), nil)
+		if err != nil {
+			return err
+		}
+		if got != int64(content.Len()) {
+			t.Errorf("want the end of the file at %d, got %d", content.Len(), got)
+		}
+		return nil
+	})
+}
+
+func TestFollowSince(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		content := "2024-03-01T10:41:00Z old\n2024-03-01T10:42:00Z new\n"
+		if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{
+			Since: time.Date(2024, 3, 1, 10, 42, 0, 0, time.UTC),
+		})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		rec, err := follow.Next()
+		if err != nil {
+			return err
+		}
+		if string(rec.Line()) != "2024-03-01T10:42:00Z new" {
+			t.Errorf("want to start with the new line, got %q", rec.Line())
+		}
+		return nil
+	})
+}
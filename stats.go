@@ -0,0 +1,117 @@
+package tailf
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of what a follower went through.
+type Stats struct {
+	BytesRead int64
+	LinesRead int64
+	// Rotations and Truncations count the times the follower had to
+	// reopen its file, and ReopenFailures the times it failed to.
+	Rotations      int64
+	Truncations    int64
+	ReopenFailures int64
+	// Dropped counts the lines dropped by the rate limit.
+	Dropped int64
+	// Blocked is the time spent in Read waiting for the file to grow.
+	Blocked time.Duration
+	// Lag is how many bytes of the file weren't read yet.
+	Lag int64
+	// Polling is set when the directory of the file couldn't be
+	// watched, and the follower fell back to polling the file.
+	Polling bool
+}
+
+// MetricsHook is told about the events of a follower as they happen.
+// Its methods are called from the follower's goroutines, and must not
+// block.
+type MetricsHook interface {
+	BytesRead(n int)
+	LinesRead(n int)
+	Rotated()
+	Truncated()
+	ReopenFailed(err error)
+	Blocked(d time.Duration)
+	Polling()
+}
+
+// counters are the atomic counterparts of Stats.
+type counters struct {
+	bytesRead      int64
+	linesRead      int64
+	rotations      int64
+	truncations    int64
+	reopenFailures int64
+	dropped        int64
+	blocked        int64
+	polling        int32
+}
+
+// Stats returns a snapshot of what the follower went through so far.
+func (f *Follower) Stats() Stats {
+	c := &f.counters
+	return Stats{
+		BytesRead:      atomic.LoadInt64(&c.bytesRead),
+		LinesRead:      atomic.LoadInt64(&c.linesRead),
+		Rotations:      atomic.LoadInt64(&c.rotations),
+		Truncations:    atomic.LoadInt64(&c.truncations),
+		ReopenFailures: atomic.LoadInt64(&c.reopenFailures),
+		Dropped:        atomic.LoadInt64(&c.dropped),
+		Blocked:        time.Duration(atomic.LoadInt64(&c.blocked)),
+		Lag:            f.Lag().Bytes,
+		Polling:        atomic.LoadInt32(&c.polling) != 0,
+	}
+}
+
+// countRead counts the bytes read, and the lines they end.
+func (f *Follower) countRead(b []byte) {
+	lines := bytes.Count(b, []byte("\n"))
+	atomic.AddInt64(&f.counters.bytesRead, int64(len(b)))
+	atomic.AddInt64(&f.counters.linesRead, int64(lines))
+	if f.opts.Metrics != nil {
+		f.opts.Metrics.BytesRead(len(b))
+		f.opts.Metrics.LinesRead(lines)
+	}
+}
+
+func (f *Follower) countReopen(truncated bool, err error) {
+	switch {
+	case err != nil:
+		atomic.AddInt64(&f.counters.reopenFailures, 1)
+		if f.opts.Metrics != nil {
+			f.opts.Metrics.ReopenFailed(err)
+		}
+	case truncated:
+		atomic.AddInt64(&f.counters.truncations, 1)
+		if f.opts.Metrics != nil {
+			f.opts.Metrics.Truncated()
+		}
+	default:
+		atomic.AddInt64(&f.counters.rotations, 1)
+		if f.opts.Metrics != nil {
+			f.opts.Metrics.Rotated()
+		}
+	}
+}
+
+func (f *Follower) countDropped() {
+	atomic.AddInt64(&f.counters.dropped, 1)
+}
+
+func (f *Follower) countBlocked(d time.Duration) {
+	atomic.AddInt64(&f.counters.blocked, int64(d))
+	if f.opts.Metrics != nil {
+		f.opts.Metrics.Blocked(d)
+	}
+}
+
+func (f *Follower) countPolling() {
+	atomic.StoreInt32(&f.counters.polling, 1)
+	if f.opts.Metrics != nil {
+		f.opts.Metrics.Polling()
+	}
+}
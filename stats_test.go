@@ -0,0 +1,71 @@
+package tailf_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+type countingHook struct {
+	mu     sync.Mutex
+	events map[string]int
+}
+
+func (h *countingHook) count(event string, n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events[event] += n
+}
+
+func (h *countingHook) BytesRead(n int)         { h.count("bytes", n) }
+func (h *countingHook) LinesRead(n int)         { h.count("lines", n) }
+func (h *countingHook) Rotated()                { h.count("rotated", 1) }
+func (h *countingHook) Truncated()              { h.count("truncated", 1) }
+func (h *countingHook) ReopenFailed(err error)  { h.count("failed", 1) }
+func (h *countingHook) Blocked(d time.Duration) { h.count("blocked", 1) }
+func (h *countingHook) Polling()                { h.count("polling", 1) }
+
+func TestStatsCountTruncations(t *testing.T) {
+	withTempFile(t, time.Second, func(t *testing.T, filename string, file *os.File) error {
+		hook := &countingHook{events: make(map[string]int)}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{Metrics: hook})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+
+		buf := make([]byte, 6)
+		if _, err := file.WriteString("hello\n"); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(follow, buf); err != nil {
+			return err
+		}
+
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt([]byte("bye\n"), 0); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(follow, buf[:4]); err != nil {
+			return err
+		}
+
+		stats := follow.Stats()
+		if stats.BytesRead != 10 || stats.LinesRead != 2 || stats.Truncations != 1 || stats.Lag != 0 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		if hook.events["bytes"] != 10 || hook.events["truncated"] != 1 {
+			t.Errorf("unexpected events: %v", hook.events)
+		}
+		return nil
+	})
+}
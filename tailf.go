@@ -16,6 +16,7 @@ package tailf
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -27,45 +28,243 @@ import (
 	"gopkg.in/fsnotify.v1"
 )
 
-type (
-	// ErrFileTruncated signifies the underlying file of a tailf.Follower
-	// has been truncated. The follower should be discarded.
-	ErrFileTruncated struct{ error }
-	// ErrFileRemoved signifies the underlying file of a tailf.Follower
-	// has been removed. The follower should be discarded.
-	ErrFileRemoved struct{ error }
-)
+// Follower is an io.ReadCloser that follows the writes to a file.
+type Follower struct {
+	// first, so the atomic counters are 64-bit aligned everywhere
+	counters counters
 
-type follower struct {
 	filename string
+	opts     Options
 
 	mu             sync.Mutex
 	notifyc        chan struct{}
 	errc           chan error
+	done           chan struct{}
+	closed         bool
+	stopped        bool
+	removal        *time.Timer
+	paused         chan struct{}
+	seeks          int32 // f.pos.seeks, for the line reader to check
+	catchup        *catchUp
+	fingerprints   map[fileID]fingerprint
 	file           *os.File
 	fileReader     *bufio.Reader
 	rotationBuffer *bytes.Buffer
-	reader         io.Reader
 	watch          *fsnotify.Watcher
+	newWatcher     func() (*fsnotify.Watcher, error)
 	size           int64
+	// behind is set while the follower might be far behind the end
+	// of its file, and reads it directly, unless readDirect is unset
+	// to compare with reading it through the buffer.
+	behind     bool
+	readDirect bool
+
+	// pos is where the next byte of fileReader is, and rotations
+	// where the bytes of rotationBuffer come from, or the previous
+	// files left to read.
+	pos       position
+	rotations []span
+
+	// lines splits the raw bytes in records, which go through
+	// the stages required by the options to become records.
+	lines   *LineReader
+	records RecordReader
+	acks    *ackTracker
+
+	lag lagTracker
+	// out is what Read consumes from when the options require
+	// the raw bytes to go through extra stages, like a Filter.
+	out io.Reader
+}
+
+// position locates a byte in the successive files of a follower.
+type position struct {
+	// gen counts the files the follower opened before this one.
+	gen    uint64
+	id     fileID
+	offset int64
+	// seeks counts the times the follower seeked before.
+	seeks int32
+}
+
+// span is a run of n bytes of rotationBuffer starting at a position, or
+// the rest of a previous file, still open, when file is set.
+type span struct {
+	position
+	n    int
+	file *os.File
+}
+
+// defaultBufferSize is the size of the buffer a file is read through,
+// the one of bufio.
+const defaultBufferSize = 4096
+
+// Options configure how a Follower reads its file.
+type Options struct {
+	// FromStart makes the follower begin reading at the start of
+	// the file instead of at its end.
+	FromStart bool
+
+	// Offset, if not zero, is where the follower begins reading,
+	// overriding FromStart. An offset past the end of the file
+	// means it was replaced by a smaller one, which is read from
+	// its start.
+	Offset int64
+
+	// Since, if not zero, has the follower begin with the first
+	// line logged at or after it, overriding Offset. The file is
+	// binary searched, using Timestamp to tell when a line was
+	// logged. Timestamp defaults to ParseTimestamp.
+	Since     time.Time
+	Timestamp func(line []byte) (time.Time, bool)
+
+	// Framer, if not nil, splits the file in the records of its
+	// frames rather than in lines, for binary files. Read then
+	// returns the records back to back, without their framing.
+	Framer Framer
+
+	// Encoding, if not Raw, is the encoding of the lines of the file,
+	// which they're transcoded to UTF-8 from. A byte order mark at
+	// the start of a file overrides it for that file, and is dropped.
+	// Offsets and checkpoints still count the bytes of the file.
+	Encoding Encoding
+
+	// Filter, if not nil, drops the lines that don't match it
+	// before they reach the reader.
+	Filter *Filter
+
+	// Dedup, if not nil, collapses the repeats of the lines left by
+	// the filter.
+	Dedup *Dedup
+
+	// Sample, if not nil, keeps only a share of the lines, after the
+	// filter. Limit, if not nil, then bounds how fast they're read.
+	Sample *Sampler
+	Limit  *RateLimit
+
+	// Checkpoint, if not nil, is where the follower saves how far
+	// its records were processed, and where it resumes from,
+	// overriding FromStart and Offset. The records returned by Next
+	// then have to be acknowledged, and the checkpoint only moves
+	// past the records that were. CheckpointInterval is how often
+	// it's saved at most, and defaults to 1s: the latest position
+	// is saved once the interval is over, and when the follower is
+	// closed.
+	Checkpoint         CheckpointStore
+	CheckpointInterval time.Duration
+
+	// Metrics, if not nil, is told about the events of the
+	// follower as they happen.
+	Metrics MetricsHook
+
+	// OnLag, if not nil, is called when the follower falls further
+	// behind its file than LagLimit. It's called again only after
+	// the follower caught up under the limit.
+	OnLag    func(Lag)
+	LagLimit Lag
+
+	// Logger, if not nil, receives the diagnostics of the follower,
+	// like the reopening of its file or the events it ignored.
+	Logger Logger
+
+	// Recovery, if not nil, is how the follower tries to recover
+	// from the errors of its watch. Without it, the first error
+	// ends the stream.
+	Recovery *RecoveryPolicy
+
+	// TruncatePolicy and RemovePolicy are what the follower does
+	// when its file is truncated, or when it or its directory is
+	// deleted. OnFileEvent is called with an ErrFileTruncated or an
+	// ErrFileRemoved for the Notify policy.
+	TruncatePolicy FilePolicy
+	RemovePolicy   FilePolicy
+	OnFileEvent    func(error)
+
+	// CatchUp, if not nil, has a follower resuming from a checkpoint
+	// in a file rotated away read what it missed from the rotated
+	// siblings of the file.
+	CatchUp *CatchUp
+
+	// RemoveTimeout, if not zero, is how long the file or its
+	// directory can be gone before the stream ends with an
+	// ErrFileRemoved.
+	RemoveTimeout time.Duration
+
+	// BufferSize is the size of the buffer the file is read through,
+	// 4096 bytes by default. While the follower is further behind the
+	// end of its file than that, it skips the buffer and reads the
+	// file directly, in reads as large as its reader asks for.
+	BufferSize int
 }
 
 // Follow returns an io.ReadCloser that follows the writes to a file.
 func Follow(filename string, fromStart bool) (io.ReadCloser, error) {
-	file, err := os.OpenFile(filename, os.O_RDONLY, 0)
+	f, err := FollowWithOptions(filename, Options{FromStart: fromStart})
 	if err != nil {
 		return nil, err
 	}
+	return f, nil
+}
+
+// FollowWithOptions returns a Follower that follows the writes to a
+// file, as configured by opts.
+func FollowWithOptions(filename string, opts Options) (*Follower, error) {
+	return follow(filename, opts, false)
+}
 
-	if !fromStart {
-		_, err := file.Seek(0, os.SEEK_END)
+// FollowWithHistory returns a Follower that reads the rotated siblings
+// of a file, oldest first and decompressed, before following the file
+// from its start. The patterns of opts.CatchUp find the siblings. When
+// resuming from a checkpoint, only what's left after it is read.
+func FollowWithHistory(filename string, opts Options) (*Follower, error) {
+	if opts.CatchUp == nil {
+		opts.CatchUp = &CatchUp{}
+	}
+	if opts.Checkpoint != nil {
+		cp, err := opts.Checkpoint.Load()
 		if err != nil {
-			_ = file.Close()
 			return nil, err
 		}
+		if cp != nil {
+			return follow(filename, opts, false)
+		}
+	}
+	opts.FromStart, opts.Offset = true, 0
+	return follow(filename, opts, true)
+}
+
+func follow(filename string, opts Options, history bool) (*Follower, error) {
+	file, err := os.OpenFile(filename, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, rotated, err := seekStart(file, opts)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	var siblings []sibling
+	switch {
+	case history:
+		siblings, err = findHistory(filename, opts.CatchUp)
+	case opts.CatchUp != nil && rotated != nil:
+		siblings, err = findCatchUp(filename, rotated, opts.CatchUp)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
 	}
 
-	reader := bufio.NewReader(file)
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+	reader := bufio.NewReaderSize(file, opts.BufferSize)
 
 	watch, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -77,21 +276,63 @@ func Follow(filename string, fromStart bool) (io.ReadCloser, error) {
 		return nil, err
 	}
 
-	f := &follower{
+	if opts.Logger == nil {
+		opts.Logger = nopLogger{}
+	}
+
+	f := &Follower{
 		filename:       absolute_path,
+		opts:           opts,
 		notifyc:        make(chan struct{}),
 		errc:           make(chan error),
+		done:           make(chan struct{}),
 		file:           file,
 		fileReader:     reader,
 		rotationBuffer: bytes.NewBuffer(nil),
-		reader:         reader,
 		watch:          watch,
+		newWatcher:     fsnotify.NewWatcher,
 		size:           0,
+		behind:         true,
+		readDirect:     true,
+		pos:            position{gen: uint64(len(siblings)), id: identify(fi), offset: offset},
+		fingerprints:   make(map[fileID]fingerprint),
+	}
+	if len(siblings) != 0 {
+		f.catchup = &catchUp{siblings: siblings}
+	}
+
+	f.lag.grew(fi.Size(), fi.ModTime())
+
+	if opts.Checkpoint != nil {
+		f.acks = newAckTracker(absolute_path, opts.Checkpoint, opts.CheckpointInterval, f.pos.id, offset)
+		f.acks.fingerprint = f.fingerprintOfFile
+	}
+	f.lines = newFollowerLineReader(f)
+	f.records = f.lines
+	if opts.Filter != nil {
+		f.records = &filterRecords{src: f.records, filter: opts.Filter}
+	}
+	if opts.Dedup != nil {
+		dr := newDedupRecords(f.records, opts.Dedup, f.done)
+		dr.seeks = &f.seeks
+		f.records = dr
+	}
+	if opts.Sample != nil {
+		f.records = &sampleRecords{src: f.records, sampler: opts.Sample}
+	}
+	if opts.Limit != nil {
+		lr := newLimitRecords(f.records, opts.Limit)
+		lr.dropped, lr.done = f.countDropped, f.done
+		f.records = lr
+	}
+	if f.records != RecordReader(f.lines) || f.acks != nil || opts.Framer != nil || opts.Encoding != Raw {
+		f.out = &recordBytes{src: f.records}
 	}
 
 	if err := watch.Add(filepath.Dir(absolute_path)); err != nil {
 		// If we can't watch the directory, we need to poll the file to see if it changes
-		go f.pollForChanges()
+		opts.Logger.Warn("tailf: can't watch directory, polling the file instead", "path", absolute_path, "err", err)
+		go f.pollForChanges(false)
 	}
 
 	go f.followFile()
@@ -99,13 +340,81 @@ func Follow(filename string, fromStart bool) (io.ReadCloser, error) {
 	return f, nil
 }
 
+// seekStart moves to where the options say to begin reading, and
+// returns that offset. When resuming from a checkpoint in a file that
+// was rotated away, the file is read from its start and the checkpoint
+// is returned.
+func seekStart(file *os.File, opts Options) (int64, *Checkpoint, error) {
+	if opts.Checkpoint != nil {
+		cp, err := opts.Checkpoint.Load()
+		if err != nil {
+			return 0, nil, err
+		}
+		if cp != nil {
+			fi, err := file.Stat()
+			if err != nil {
+				return 0, nil, err
+			}
+			if !cp.inFile(file, fi) {
+				offset, err := file.Seek(0, os.SEEK_SET)
+				return offset, cp, err
+			}
+			offset, err := file.Seek(cp.Offset, os.SEEK_SET)
+			return offset, nil, err
+		}
+	}
+
+	var (
+		offset int64
+		err    error
+	)
+	switch {
+	case !opts.Since.IsZero():
+		var fi os.FileInfo
+		fi, err = file.Stat()
+		if err != nil {
+			return 0, nil, err
+		}
+		offset, err = sinceOffset(file, fi.Size(), opts.Since, opts.Timestamp)
+		if err != nil {
+			return 0, nil, err
+		}
+		offset, err = file.Seek(offset, os.SEEK_SET)
+	case opts.Offset != 0:
+		var fi os.FileInfo
+		fi, err = file.Stat()
+		if err != nil {
+			return 0, nil, err
+		}
+		offset = opts.Offset
+		if offset > fi.Size() {
+			offset = 0
+		}
+		offset, err = file.Seek(offset, os.SEEK_SET)
+	case !opts.FromStart:
+		offset, err = file.Seek(0, os.SEEK_END)
+	}
+	return offset, nil, err
+}
+
 // Close will remove the watch on the file. Subsequent reads to the file
-// will eventually reach EOF.
-func (f *follower) Close() error {
+// will eventually reach EOF. The last position acknowledged is saved
+// to the checkpoint, if the follower keeps one.
+func (f *Follower) Close() error {
+	var serr error
+	if f.acks != nil {
+		serr = f.acks.flush()
+	}
+
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		close(f.done)
+	}
 	werr := f.watch.Close()
 	cerr := f.file.Close()
+	f.dropRotations()
 	switch {
 	case werr != nil && cerr == nil:
 		return werr
@@ -114,12 +423,57 @@ func (f *follower) Close() error {
 	case werr != nil && cerr != nil:
 		return fmt.Errorf("couldn't remove watch (%v) and close file (%v)", werr, cerr)
 	}
-	return nil
+	return serr
 }
 
-func (f *follower) Read(b []byte) (int, error) {
+// Read reads from the followed file, blocking until data is
+// available. Use either Read or Next on a follower, not both.
+func (f *Follower) Read(b []byte) (int, error) {
+	if f.out != nil {
+		return f.out.Read(b)
+	}
+	n, _, err := f.read(b)
+	return n, err
+}
+
+// Next returns the next line of the followed file, blocking until there
+// is one. If the follower keeps a checkpoint, the record must be
+// acknowledged once processed.
+func (f *Follower) Next() (*Record, error) {
+	return f.records.Next()
+}
+
+// read reads the raw bytes of the followed file, and tells where they
+// come from. All the bytes read come from the same file.
+func (f *Follower) read(b []byte) (int, position, error) {
+	if !f.waitResumed() {
+		return 0, position{}, io.EOF
+	}
+	f.mu.Lock()
+	catchup := f.catchup
+	f.mu.Unlock()
+	if catchup != nil {
+		// what was missed in the rotated files comes first
+		n, at, err := catchup.read(f, b)
+		if err != io.EOF {
+			f.countRead(b[:n])
+			return n, at, err
+		}
+		f.mu.Lock()
+		if f.catchup == catchup {
+			f.catchup = nil
+		}
+		f.mu.Unlock()
+	}
+
 	f.mu.Lock()
 
+	if n, at, ok := f.readBehind(b); ok {
+		f.mu.Unlock()
+		f.countRead(b[:n])
+		return n, at, nil
+	}
+
 	// Refill the buffer
 	_, err := f.fileReader.Peek(1)
 	switch err { // some errors are expected
@@ -135,103 +489,198 @@ func (f *follower) Read(b []byte) (int, error) {
 		if ok && perr.Err == syscall.Errno(syscall.EBADF) {
 			// bad file number will likely be replaced by
 			// a new file on an inotify event, so carry on
+			f.opts.Logger.Debug("tailf: bad file descriptor, waiting for the file to be reopened", "path", f.filename)
 		} else {
 			f.mu.Unlock()
-			return 0, err
+			return 0, position{}, err
 		}
 	}
 	readable := f.fileReader.Buffered()
-
-	// check for errors before doing anything
-	select {
-	case err, open := <-f.errc:
-		if !open && readable != 0 {
-			break
-		}
-		f.mu.Unlock()
-		if !open {
-			return 0, io.EOF
+	if len(f.rotations) != 0 {
+		// drain what's left of the previous files first
+		readable = f.rotations[0].n
+		if f.rotations[0].file != nil {
+			readable = len(b)
 		}
-		return 0, err
-	default:
 	}
 
+	// errors only come once what was read before them is handed out
 	if readable == 0 {
 		f.mu.Unlock()
+		f.caughtUp()
 
 		// wait for the file to grow
-		_, open := <-f.notifyc
-		if !open {
-			return 0, io.EOF
+		start := time.Now()
+		select {
+		case _, open := <-f.notifyc:
+			f.countBlocked(time.Since(start))
+			if !open {
+				return 0, position{}, io.EOF
+			}
+		case err := <-f.errc:
+			f.countBlocked(time.Since(start))
+			return 0, position{}, err
 		}
 		// then let the reader try again
-		return 0, nil
+		return 0, position{}, nil
 	}
 
-	n, err := f.reader.Read(b[:imin(readable, len(b))])
+	var (
+		n  int
+		at position
+	)
+	if len(f.rotations) != 0 {
+		rot := &f.rotations[0]
+		at = rot.position
+		if rot.file != nil {
+			n, err = rot.file.Read(b)
+			if err == io.EOF {
+				// the rest of the file is read
+				_ = rot.file.Close()
+				rot.file, err = nil, nil
+			}
+		} else {
+			n, err = f.rotationBuffer.Read(b[:imin(readable, len(b))])
+			rot.n -= n
+		}
+		rot.offset += int64(n)
+		if rot.n == 0 && rot.file == nil {
+			f.rotations = f.rotations[1:]
+		}
+	} else {
+		at = f.pos
+		n, err = f.fileReader.Read(b[:imin(readable, len(b))])
+		f.pos.offset += int64(n)
+	}
 	f.mu.Unlock()
+	f.countRead(b[:n])
 
-	return n, err
+	return n, at, err
+}
+
+// readBehind reads the file directly, skipping the buffer, while the
+// follower is behind. It returns false once it caught up, to carry on
+// with the buffer. It must be called with the lock held.
+func (f *Follower) readBehind(b []byte) (int, position, bool) {
+	if !f.readDirect || !f.behind || len(f.rotations) != 0 || f.fileReader.Buffered() != 0 {
+		return 0, position{}, false
+	}
+	// the buffer is empty, reads as large as it go straight to the file
+	at := f.pos
+	n, err := f.fileReader.Read(b)
+	f.pos.offset += int64(n)
+	if n < len(b) || err != nil {
+		f.behind = false
+	}
+	return n, at, n != 0
 }
 
-func (f *follower) followFile() {
-	defer f.watch.Close()
-	defer close(f.notifyc)
-	defer close(f.errc)
+func (f *Follower) followFile() {
+	defer func() { f.watch.Close() }()
+	defer func() {
+		f.mu.Lock()
+		f.stopped = true
+		close(f.notifyc)
+		f.mu.Unlock()
+	}()
+	failures := 0
 	for {
+		var err error
 		select {
 		case ev, open := <-f.watch.Events:
 			if !open {
 				return
 			}
-			if pathEqual(ev.Name, f.filename) {
-				err := f.handleFileEvent(ev)
-				if err != nil {
-					f.errc <- err
-					return
-				}
+			switch {
+			case pathEqual(ev.Name, f.filename):
+				err = f.handleFileEvent(ev)
+			case isDirGone(ev) && pathEqual(ev.Name, filepath.Dir(f.filename)):
+				err = f.waitForDir()
 			}
-		case err, open := <-f.watch.Errors:
+		case werr, open := <-f.watch.Errors:
 			if !open {
 				return
 			}
-			if err != nil {
-				f.errc <- err
+			err = werr
+		case <-f.removalTimeout():
+			err = f.checkRemoved()
+		}
+
+		if err != nil {
+			failures++
+			switch f.recoverFrom(err, failures) {
+			case giveUp:
+				f.opts.Logger.Warn("tailf: stopping on error", "path", f.filename, "err", err)
+				f.fail(err)
+				return
+			case poll:
+				f.opts.Logger.Warn("tailf: watch keeps failing, polling the file instead", "path", f.filename, "err", err)
+				f.pollForChanges(true)
 				return
+			case recovered:
+				failures = 0
 			}
+		} else {
+			failures = 0
 		}
 
-		select {
-		case f.notifyc <- struct{}{}:
-			// try to wake up whoever was waiting on an update
-		default:
-			// otherwise just wait for the next event
-		}
+		f.checkLag()
+		f.notify()
 	}
 }
 
-func (f *follower) handleFileEvent(ev fsnotify.Event) error {
+// notify wakes up the reader waiting on an update, if there is one.
+func (f *Follower) notify() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return
+	}
+	select {
+	case f.notifyc <- struct{}{}:
+		// try to wake up whoever was waiting on an update
+	default:
+		// otherwise just wait for the next event
+	}
+}
+
+// fail hands an error to the reader, unless the follower is closed
+// first.
+func (f *Follower) fail(err error) {
+	select {
+	case f.errc <- err:
+	case <-f.done:
+	}
+}
+
+func (f *Follower) handleFileEvent(ev fsnotify.Event) error {
 	switch {
 	case isOp(ev, fsnotify.Create):
 		// new file created with the same name
-		return f.reopenFile()
+		f.recreated()
+		return f.reopenFile(false)
 
 	case isOp(ev, fsnotify.Write):
 		// On write, check to see if the file has been truncated
 		// If not, insure the bufio buffer is full
-		switch f.checkForTruncate() {
-		case nil:
+		err := f.checkForTruncate()
+		switch {
+		case err == nil:
 			return f.fillFileBuffer()
-		case ErrFileRemoved{}:
+		case errors.Is(err, ErrRemoved):
 			// If file was written to and then removed before we could even Stat the file, just wait for the next creation
+			f.opts.Logger.Debug("tailf: ignoring write to removed file", "path", f.filename)
 			return nil
+		case errors.Is(err, ErrTruncated):
+			return f.truncated(err)
 		default:
-			return f.reopenFile()
+			return f.reopenFile(false)
 		}
 
 	case isOp(ev, fsnotify.Remove), isOp(ev, fsnotify.Rename):
 		// wait for a new file to be created
-		return nil
+		f.opts.Logger.Debug("tailf: file went away, waiting for a new one", "path", f.filename, "event", ev.String())
+		return f.removed(isOp(ev, fsnotify.Remove))
 
 	case isOp(ev, fsnotify.Chmod):
 		// Modified time on the file changed, noop
@@ -242,51 +691,108 @@ func (f *follower) handleFileEvent(ev fsnotify.Event) error {
 	}
 }
 
-func (f *follower) reopenFile() error {
+// reopenFile switches to the file now at the follower's path, because
+// the previous one was rotated away or truncated.
+func (f *Follower) reopenFile(truncated bool) (err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	_, err := os.Stat(f.filename)
+	f.opts.Logger.Debug("tailf: reopening file", "path", f.filename, "truncated", truncated)
+	_, err = os.Stat(f.filename)
 	if os.IsNotExist(err) {
 		// File disappeared too quickly, wait for next rotation
+		f.opts.Logger.Debug("tailf: file disappeared before it could be reopened", "path", f.filename)
 		return nil
 	}
+	defer func() {
+		if err != nil {
+			f.opts.Logger.Warn("tailf: couldn't reopen file", "path", f.filename, "err", err)
+		}
+		f.countReopen(truncated, err)
+	}()
 	if err != nil {
 		return err
 	}
 
-	if err := f.file.Close(); err != nil {
+	if f.acks != nil {
+		// last chance to fingerprint the file, to find it once rotated
+		f.refreshFingerprint()
+	}
+	file, err := os.OpenFile(f.filename, os.O_RDONLY, 0)
+	if err != nil {
 		return err
 	}
-
-	f.file, err = os.OpenFile(f.filename, os.O_RDONLY, 0)
+	fi, err := file.Stat()
 	if err != nil {
+		_ = file.Close()
 		return err
 	}
 
-	// recover buffered bytes
-	unreadByteCount := f.rotationBuffer.Len() + f.fileReader.Buffered()
-	buf := bytes.NewBuffer(make([]byte, unreadByteCount))
-
-	n, err := f.reader.Read(buf.Bytes())
-	if err != nil {
+	// recover the buffered bytes, they'll be read before the new file
+	if unread := f.fileReader.Buffered(); unread != 0 {
+		buf, err := f.fileReader.Peek(unread)
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		f.rotationBuffer.Write(buf)
+		f.rotations = append(f.rotations, span{position: f.pos, n: unread})
+	}
+	if !truncated {
+		// the rest of the rotated file is read before the new one,
+		// however far behind the reader is
+		rest := f.pos
+		rest.offset += int64(f.fileReader.Buffered())
+		f.rotations = append(f.rotations, span{position: rest, file: f.file})
+	} else if err := f.file.Close(); err != nil {
+		_ = file.Close()
 		return err
-	} else if n != unreadByteCount {
-		return fmt.Errorf("failed to flush the buffer completely: Actual(%d) | Expected(%d) | buf_len(%d)", n, unreadByteCount, buf.Len())
 	}
+	f.file = file
 
 	f.fileReader.Reset(f.file)
-	f.rotationBuffer = buf
+	f.behind = true
+	f.pos = position{gen: f.pos.gen + 1, id: identify(fi), seeks: f.pos.seeks}
+	f.size = fi.Size()
+	f.lag.reset()
+	f.lag.grew(fi.Size(), fi.ModTime())
 
-	// append buffered bytes before the new file
-	f.reader = io.MultiReader(f.rotationBuffer, f.fileReader)
+	return nil
+}
 
-	return err
+// dropRotations drops what's left to read of the previous files. It must
+// be called with the lock held.
+func (f *Follower) dropRotations() {
+	for _, rot := range f.rotations {
+		if rot.file != nil {
+			_ = rot.file.Close()
+		}
+	}
+	f.rotationBuffer.Reset()
+	f.rotations = nil
 }
 
-func (f *follower) fillFileBuffer() error {
+// pendingRotated returns how many bytes are left to read of the previous
+// files. It must be called with the lock held.
+func (f *Follower) pendingRotated() int64 {
+	pending := int64(f.rotationBuffer.Len())
+	for _, rot := range f.rotations {
+		if rot.file == nil {
+			continue
+		}
+		if fi, err := rot.file.Stat(); err == nil && fi.Size() > rot.offset {
+			pending += fi.Size() - rot.offset
+		}
+	}
+	return pending
+}
+
+func (f *Follower) fillFileBuffer() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if f.paused != nil {
+		return nil
+	}
 
 	_, err := f.fileReader.Peek(1) // Refill the buffer
 	switch err {
@@ -302,77 +808,122 @@ func (f *follower) fillFileBuffer() error {
 // Note: if the file gets truncated, and before the size can be stat'd,
 // it has regrown to be >= the same size as previously, the truncate
 // will be missed. tl;dr, don't use copy-truncate...
-func (f *follower) checkForTruncate() error {
+func (f *Follower) checkForTruncate() error {
 	f.mu.Lock()
 
 	fi, err := os.Stat(f.filename)
+	current, read := f.pos.id, f.pos.offset
 
 	f.mu.Unlock()
 	if os.IsNotExist(err) {
-		return ErrFileRemoved{fmt.Errorf("file was removed: %v", f.filename)}
+		return ErrFileRemoved{Path: f.filename, Inode: current.Ino, Offset: read, Err: err}
 	}
 	if err != nil {
 		return err
 	}
+	if identify(fi) != current {
+		// another file took its place, wait for its creation event
+		return nil
+	}
 
+	f.lag.grew(fi.Size(), fi.ModTime())
+
+	// the file shrank, or is now smaller than what was read of it
 	newSize := fi.Size()
-	if newSize < f.size {
-		err = ErrFileTruncated{fmt.Errorf("file (%s) was truncated", f.filename)}
+	if newSize < f.size || newSize < read {
+		err = ErrFileTruncated{Path: f.filename, Inode: current.Ino, Offset: read, Size: newSize}
+	}
+	if newSize-read > int64(f.opts.BufferSize) {
+		f.mu.Lock()
+		f.behind = true
+		f.mu.Unlock()
 	}
 
 	f.size = newSize
 	return err
 }
 
-// This is here for situations where the directory the watched file sits in can't be inotified on
-func (f *follower) pollForChanges() {
+// This is here for situations where the directory the watched file sits in can't be inotified on.
+// When full is set, the watch isn't working at all and the file's growth
+// is polled as well.
+func (f *Follower) pollForChanges(full bool) {
+	f.countPolling()
+
 	previousFile, err := f.file.Stat()
 	if err != nil {
-		f.errc <- err
+		f.fail(err)
 	}
 
-	if err := f.watch.Add(f.filename); err != nil {
-		f.errc <- err
+	if !full {
+		if err := f.addWatch(f.filename); err != nil {
+			f.fail(err)
+		}
 	}
 
+	missing := false
 	for {
-		currentFile, err := os.Stat(f.filename)
-
-		switch err {
-		case nil:
-			switch os.SameFile(currentFile, previousFile) {
-			case true:
-				// No change, do nothing
-				break
-			case false:
-				previousFile = currentFile
-				if err := f.reopenFile(); err != nil {
-					f.errc <- err
-				}
-
-				if err := f.watch.Add(f.filename); err != nil {
-					f.errc <- err
-				}
-
-				select {
-				case f.notifyc <- struct{}{}:
-					// try to wake up whoever was waiting on an update
-				default:
-					// otherwise just wait for the next event
-				}
+		if full {
+			if err := f.rescan(); err != nil {
+				f.opts.Logger.Warn("tailf: stopping on polling error", "path", f.filename, "err", err)
+				f.fail(err)
+				return
 			}
-		default:
-			// Filename doens't seem to be there, wait for it to re-appear
+			f.checkLag()
+			f.notify()
+		} else {
+			previousFile, missing = f.pollOnce(previousFile, missing)
 		}
 
-		time.Sleep(time.Second)
+		if !f.sleep(time.Second) {
+			return
+		}
+	}
+}
+
+// pollOnce checks if the file was replaced since it was last seen.
+func (f *Follower) pollOnce(previousFile os.FileInfo, missing bool) (os.FileInfo, bool) {
+	currentFile, err := os.Stat(f.filename)
+	if err != nil {
+		// Filename doens't seem to be there, wait for it to re-appear
+		if !missing {
+			f.opts.Logger.Debug("tailf: polled file is missing, waiting for it", "path", f.filename, "err", err)
+		}
+		return previousFile, true
+	}
+	if missing {
+		f.opts.Logger.Debug("tailf: polled file is back", "path", f.filename)
+	}
+	if os.SameFile(currentFile, previousFile) {
+		// No change, do nothing
+		return previousFile, false
+	}
+
+	if err := f.reopenFile(false); err != nil {
+		f.fail(err)
+	}
+	if err := f.addWatch(f.filename); err != nil {
+		f.fail(err)
 	}
+	f.notify()
+	return currentFile, false
+}
+
+// addWatch adds a path to the current watch of the follower.
+func (f *Follower) addWatch(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.watch.Add(name)
 }
 
 func isOp(ev fsnotify.Event, op fsnotify.Op) bool {
 	return ev.Op&op == op
 }
 
+// isDirGone tells if a watched directory was removed or renamed.
+func isDirGone(ev fsnotify.Event) bool {
+	return isOp(ev, fsnotify.Remove) || isOp(ev, fsnotify.Rename)
+}
+
 func pathEqual(lhs, rhs string) bool {
 	var err error
 	lhs, err = filepath.Abs(lhs)
@@ -392,3 +943,10 @@ func imin(a, b int) int {
 	}
 	return b
 }
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -463,6 +464,189 @@ func TestPollingReader(t *testing.T) {
 	})
 }
 
+func TestReadLargeFile(t *testing.T) {
+	withTempFile(t, 5*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		want := make([]byte, 3<<20)
+		rand.New(rand.NewSource(1)).Read(want)
+		if _, err := file.Write(want); err != nil {
+			return err
+		}
+
+		for _, size := range []int{0, 1 << 10, 1 << 16, 1 << 20} {
+			follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, BufferSize: size})
+			if err != nil {
+				return fmt.Errorf("failed creating tailf.follower: %v", err)
+			}
+			got := make([]byte, len(want))
+			_, err = io.ReadFull(follow, got)
+			follow.Close()
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("buffer of %d bytes: read something else than the file", size)
+			}
+		}
+		return nil
+	})
+}
+
+func TestRotationFarBehind(t *testing.T) {
+	withTempFile(t, 5*time.Second, func(t *testing.T, filename string, file *os.File) error {
+		old := make([]byte, 160<<10)
+		rand.New(rand.NewSource(1)).Read(old)
+		if _, err := file.Write(old); err != nil {
+			return err
+		}
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			return fmt.Errorf("failed creating tailf.follower: %v", err)
+		}
+		defer follow.Close()
+		got := make([]byte, len(old)+len("new\n"))
+		if _, err := io.ReadFull(follow, got[:16]); err != nil {
+			return err
+		}
+
+		// rotate while most of the file is still to be read
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, []byte("new\n"), 0600); err != nil {
+			return err
+		}
+		for follow.Stats().Rotations == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if _, err := io.ReadFull(follow, got[16:]); err != nil {
+			return err
+		}
+		if !bytes.Equal(got[:len(old)], old) || string(got[len(old):]) != "new\n" {
+			t.Error("want the rest of the rotated file before the new one")
+		}
+		return nil
+	})
+}
+
+// benchDir returns a directory removed once the benchmark is done.
+func benchDir(b *testing.B) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailf_bench_dir")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// largeFile returns a file of size bytes, in lines of 128 bytes.
+func largeFile(b *testing.B, size int) string {
+	filename := path.Join(benchDir(b), "app.log")
+	line := []byte(strings.Repeat("x", 127) + "\n")
+	data := bytes.Repeat(line, size/len(line))
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		b.Fatal(err)
+	}
+	return filename
+}
+
+// Read a file from its start, like when catching up after a restart,
+// reading it directly or through the buffer
+func BenchmarkCatchUp(b *testing.B) {
+	const size = 64 << 20
+	filename := largeFile(b, size)
+	for _, bufSize := range []int{4 << 10, 64 << 10, 1 << 20} {
+		for _, direct := range []bool{true, false} {
+			mode := "buffered"
+			if direct {
+				mode = "direct"
+			}
+			b.Run(fmt.Sprintf("buffer=%dKB/%s", bufSize>>10, mode), func(b *testing.B) {
+				b.SetBytes(size)
+				buf := make([]byte, bufSize)
+				for i := 0; i < b.N; i++ {
+					follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, BufferSize: bufSize})
+					if err != nil {
+						b.Fatal(err)
+					}
+					tailf.SetReadDirect(follow, direct)
+					for read := 0; read < size; {
+						n, err := follow.Read(buf)
+						if err != nil {
+							b.Fatal(err)
+						}
+						read += n
+					}
+					follow.Close()
+				}
+			})
+		}
+	}
+}
+
+// Time from a write to the file to Read returning it
+func BenchmarkLatency(b *testing.B) {
+	filename := path.Join(benchDir(b), "app.log")
+	file, err := os.Create(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+	follow, err := tailf.Follow(filename, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer follow.Close()
+
+	line := []byte("hello\n")
+	buf := make([]byte, len(line))
+	latencies := make([]time.Duration, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		if _, err := file.Write(line); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.ReadFull(follow, buf); err != nil {
+			b.Fatal(err)
+		}
+		latencies[i] = time.Since(start)
+	}
+	b.StopTimer()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[b.N/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(latencies[b.N*99/100].Nanoseconds()), "p99-ns")
+}
+
+// Time from a file being rotated to Read returning the first line of the
+// new one
+func BenchmarkRotation(b *testing.B) {
+	filename := path.Join(benchDir(b), "app.log")
+	if err := ioutil.WriteFile(filename, nil, 0600); err != nil {
+		b.Fatal(err)
+	}
+	follow, err := tailf.Follow(filename, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer follow.Close()
+
+	line := []byte("hello\n")
+	buf := make([]byte, len(line))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			b.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, line, 0600); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.ReadFull(follow, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func withTempFile(t *testing.T, timeout time.Duration, action func(t *testing.T, filename string, file *os.File) error) {
 	dir, err := ioutil.TempDir(os.TempDir(), "tailf_test_dir")
 	if err != nil {
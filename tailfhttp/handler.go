@@ -0,0 +1,283 @@
+/*
+Package tailfhttp serves followed files over HTTP, so they can be tailed
+from a browser or with curl.
+
+Clients pick a file with the `path` query parameter, which must be
+allowed by the Handler. The stream starts at the end of the file, unless
+one of these parameters says otherwise:
+
+	n=100       start 100 lines before the end
+	offset=42   start at byte 42
+	grep=RE     only send the lines matching RE (repeatable)
+	grep-v=RE   don't send the lines matching RE (repeatable)
+
+Browsers asking for `text/event-stream` get Server-Sent Events, whose
+IDs locate the end of the line as dev:ino:offset, the file it's in and
+the byte offset in it: a reconnecting EventSource sends the last one in
+the Last-Event-ID header and resumes where it left off, in the rotated
+file if that's where it was. Browsers asking
+for `text/html` get a page that does just that. Everybody else gets the
+lines as they come, in a chunked response.
+
+WebSocket clients, only from the pages of the same host by default,
+receive a JSON Message per line, and can send Control messages to pause
+and resume the stream, change its filter or seek back to the last lines
+of the file:
+
+	{"op": "pause"}
+	{"op": "resume"}
+	{"op": "filter", "grep": ["ERROR"], "grepV": ["healthcheck"]}
+	{"op": "seek", "lines": 100}
+*/
+package tailfhttp
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aybabtme/tailf"
+)
+
+// Handler is an http.Handler streaming followed files.
+type Handler struct {
+	// Allow lists the files that can be served, as filepath.Match
+	// patterns of absolute paths. Nothing is served if it's empty.
+	Allow []string
+
+	// CheckOrigin tells if a WebSocket connection can be opened for
+	// a request, by its Origin: unlike the other requests, browsers
+	// let any page open one. Defaults to only allowing the pages of
+	// the host the request is for.
+	CheckOrigin func(r *http.Request) bool
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filename, ok := h.allowed(r.URL.Query().Get("path"))
+	if !ok {
+		http.Error(w, "path not allowed", http.StatusForbidden)
+		return
+	}
+
+	if isWebSocket(r) {
+		h.serveWebSocket(w, r, filename)
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = pageTmpl.Execute(w, r.URL.RawQuery)
+		return
+	}
+	sse := strings.Contains(accept, "text/event-stream")
+
+	req, err := parseRequest(r, filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	follow, err := tailf.FollowWithOptions(filename, req.opts)
+	if err != nil {
+		http.Error(w, "can't follow file", http.StatusNotFound)
+		return
+	}
+	defer follow.Close()
+
+	go func() {
+		// unblock the reads once the client is gone
+		<-r.Context().Done()
+		follow.Close()
+	}()
+
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	flush(w)
+
+	for {
+		rec, err := follow.Next()
+		if err != nil {
+			return
+		}
+		rec.Ack()
+		if !req.filter.Match(rec.Data) {
+			continue
+		}
+		if sse {
+			next := rec.FileOffset + int64(len(rec.Data))
+			err = writeEvent(w, fmt.Sprintf("%d:%d:%d", rec.Dev, rec.Ino, next), rec.Line())
+		} else {
+			_, err = w.Write(rec.Data)
+		}
+		if err != nil {
+			return
+		}
+		flush(w)
+	}
+}
+
+// writeEvent writes a Server-Sent Event. A line can hold a CR, which
+// would end the data field: each part of it goes in a field of its own,
+// which the client joins with a LF.
+func writeEvent(w io.Writer, id string, line []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id: %s\n", id)
+	for _, part := range bytes.Split(line, []byte("\r")) {
+		fmt.Fprintf(&buf, "data: %s\n", part)
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (h *Handler) allowed(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	for _, pattern := range h.Allow {
+		if ok, _ := filepath.Match(pattern, abs); ok {
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+type request struct {
+	opts   tailf.Options
+	filter *tailf.Filter
+}
+
+func parseRequest(r *http.Request, filename string) (*request, error) {
+	q := r.URL.Query()
+	req := &request{}
+
+	filter, err := compileFilter(q["grep"], q["grep-v"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %v", err)
+	}
+	req.filter = filter
+
+	if id := r.Header.Get("Last-Event-ID"); strings.Contains(id, ":") {
+		cp, err := parseEventID(id, filename)
+		if err != nil {
+			return nil, err
+		}
+		// resume in the file of the event, even if it was rotated
+		req.opts = tailf.Options{Checkpoint: eventStore{cp}, CatchUp: &tailf.CatchUp{}}
+		return req, nil
+	}
+
+	var start int64
+	offset := r.Header.Get("Last-Event-ID")
+	if offset == "" {
+		offset = q.Get("offset")
+	}
+	if offset != "" {
+		start, err = strconv.ParseInt(offset, 10, 64)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid offset %q", offset)
+		}
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return nil, fmt.Errorf("can't stat file")
+		}
+		if start > fi.Size() {
+			// the file was replaced, start over
+			start = 0
+		}
+	} else {
+		// without n, start at the end of the file
+		n := 0
+		if q.Get("n") != "" {
+			n, err = strconv.Atoi(q.Get("n"))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid line count %q", q.Get("n"))
+			}
+		}
+		start, err = tailf.LastLinesOffset(filename, n)
+		if err != nil {
+			return nil, fmt.Errorf("can't find the last %d lines", n)
+		}
+	}
+	req.opts = tailf.Options{FromStart: true, Offset: start}
+	return req, nil
+}
+
+// parseEventID parses the dev:ino:offset ID of an event.
+func parseEventID(id, filename string) (tailf.Checkpoint, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 3 {
+		return tailf.Checkpoint{}, fmt.Errorf("invalid event ID %q", id)
+	}
+	dev, err1 := strconv.ParseUint(parts[0], 10, 64)
+	ino, err2 := strconv.ParseUint(parts[1], 10, 64)
+	offset, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || offset < 0 {
+		return tailf.Checkpoint{}, fmt.Errorf("invalid event ID %q", id)
+	}
+	return tailf.Checkpoint{Path: filename, Dev: dev, Ino: ino, Offset: offset}, nil
+}
+
+// eventStore is the checkpoint of the last event a client received. It
+// only lasts as long as the request, the client keeping the next one.
+type eventStore struct {
+	cp tailf.Checkpoint
+}
+
+func (s eventStore) Load() (*tailf.Checkpoint, error) {
+	cp := s.cp
+	return &cp, nil
+}
+
+func (s eventStore) Save(cp tailf.Checkpoint) error {
+	return nil
+}
+
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+var pageTmpl = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>tailf</title></head>
+<body>
+<pre id="log"></pre>
+<script>
+var log = document.getElementById("log");
+var src = new EventSource("?" + {{.}});
+src.onmessage = function(e) {
+	log.appendChild(document.createTextNode(e.data + "\n"));
+	window.scrollTo(0, document.body.scrollHeight);
+};
+</script>
+</body>
+</html>
+`))
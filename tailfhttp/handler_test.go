@@ -0,0 +1,183 @@
+package tailfhttp_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aybabtme/tailf/tailfhttp"
+)
+
+func withServer(t *testing.T, content string, action func(srv *httptest.Server, filename string, file *os.File)) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailfhttp_test_dir")
+	if err != nil {
+		t.Fatalf("couldn't create temp dir: '%v'", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("couldn't create temp file: '%v'", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&tailfhttp.Handler{Allow: []string{filepath.Join(dir, "*.log")}})
+	defer srv.Close()
+
+	action(srv, filename, file)
+}
+
+func get(t *testing.T, srv *httptest.Server, query url.Values, header http.Header) *http.Response {
+	req, err := http.NewRequest("GET", srv.URL+"/?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestHandlerRejectsUnlistedPaths(t *testing.T) {
+	withServer(t, "", func(srv *httptest.Server, filename string, file *os.File) {
+		resp := get(t, srv, url.Values{"path": {"/etc/passwd"}}, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("want status %d, got %d", http.StatusForbidden, resp.StatusCode)
+		}
+	})
+}
+
+func TestHandlerStreamsLastLines(t *testing.T) {
+	withServer(t, "one\ntwo\nERROR three\nfour\n", func(srv *httptest.Server, filename string, file *os.File) {
+		resp := get(t, srv, url.Values{"path": {filename}, "n": {"3"}, "grep-v": {"ERROR"}}, nil)
+		defer resp.Body.Close()
+
+		if _, err := file.WriteString("five\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		lines := bufio.NewReader(resp.Body)
+		for _, want := range []string{"two\n", "four\n", "five\n"} {
+			got, err := lines.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+		}
+	})
+}
+
+// event is a Server-Sent Event.
+type event struct {
+	id, data string
+}
+
+func readEvents(t *testing.T, events *bufio.Reader, n int) []event {
+	var got []event
+	var ev event
+	for len(got) < n {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if ev.data != "" {
+				ev.data += "\n"
+			}
+			ev.data += strings.TrimPrefix(line, "data: ")
+		case line == "":
+			got = append(got, ev)
+			ev = event{}
+		}
+	}
+	return got
+}
+
+func TestHandlerResumesEventStream(t *testing.T) {
+	withServer(t, "one\ntwo\nthree\n", func(srv *httptest.Server, filename string, file *os.File) {
+		header := http.Header{
+			"Accept":        {"text/event-stream"},
+			"Last-Event-Id": {"4"},
+		}
+		resp := get(t, srv, url.Values{"path": {filename}}, header)
+		defer resp.Body.Close()
+
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("want an event stream, got %q", ct)
+		}
+
+		got := readEvents(t, bufio.NewReader(resp.Body), 2)
+		for i, want := range []event{{":8", "two"}, {":14", "three"}} {
+			if !strings.HasSuffix(got[i].id, want.id) || got[i].data != want.data {
+				t.Errorf("want %q with an ID ending in %q, got %+v", want.data, want.id, got[i])
+			}
+		}
+	})
+}
+
+func TestHandlerResumesEventStreamAcrossRotation(t *testing.T) {
+	withServer(t, "one\ntwo\n", func(srv *httptest.Server, filename string, file *os.File) {
+		header := http.Header{"Accept": {"text/event-stream"}}
+		resp := get(t, srv, url.Values{"path": {filename}, "offset": {"0"}}, header)
+		first := readEvents(t, bufio.NewReader(resp.Body), 1)[0]
+		resp.Body.Close()
+
+		// the file rotates while the client is away
+		if _, err := file.WriteString("three\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filename, filename+".1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte("four\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		header.Set("Last-Event-Id", first.id)
+		resp = get(t, srv, url.Values{"path": {filename}}, header)
+		defer resp.Body.Close()
+
+		got := readEvents(t, bufio.NewReader(resp.Body), 3)
+		for i, want := range []string{"two", "three", "four"} {
+			if got[i].data != want {
+				t.Errorf("want %q, got %+v", want, got[i])
+			}
+		}
+	})
+}
+
+func TestHandlerSendsCarriageReturnsInEvents(t *testing.T) {
+	withServer(t, "50%\r100%\ndone\n", func(srv *httptest.Server, filename string, file *os.File) {
+		header := http.Header{"Accept": {"text/event-stream"}}
+		resp := get(t, srv, url.Values{"path": {filename}, "offset": {"0"}}, header)
+		defer resp.Body.Close()
+
+		got := readEvents(t, bufio.NewReader(resp.Body), 2)
+		for i, want := range []string{"50%\n100%", "done"} {
+			if got[i].data != want {
+				t.Errorf("want %q, got %+v", want, got[i])
+			}
+		}
+	})
+}
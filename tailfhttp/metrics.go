@@ -0,0 +1,92 @@
+package tailfhttp
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aybabtme/tailf"
+)
+
+// Metrics is an http.Handler exposing the Stats of followers in the
+// Prometheus text format. Each follower is labelled with the name it
+// was added with.
+type Metrics struct {
+	mu        sync.Mutex
+	followers map[string]*tailf.Follower
+}
+
+// Add starts exposing the stats of a follower, replacing the one that
+// had the same name.
+func (m *Metrics) Add(name string, f *tailf.Follower) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.followers == nil {
+		m.followers = make(map[string]*tailf.Follower)
+	}
+	m.followers[name] = f
+}
+
+// Remove stops exposing the stats of a follower.
+func (m *Metrics) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.followers, name)
+}
+
+type metric struct {
+	name  string
+	typ   string
+	help  string
+	value func(s tailf.Stats) float64
+}
+
+var metrics = []metric{
+	{"tailf_read_bytes_total", "counter", "Bytes read from the file.",
+		func(s tailf.Stats) float64 { return float64(s.BytesRead) }},
+	{"tailf_read_lines_total", "counter", "Lines read from the file.",
+		func(s tailf.Stats) float64 { return float64(s.LinesRead) }},
+	{"tailf_rotations_total", "counter", "Times the file was rotated.",
+		func(s tailf.Stats) float64 { return float64(s.Rotations) }},
+	{"tailf_truncations_total", "counter", "Times the file was truncated.",
+		func(s tailf.Stats) float64 { return float64(s.Truncations) }},
+	{"tailf_reopen_failures_total", "counter", "Times the file couldn't be reopened.",
+		func(s tailf.Stats) float64 { return float64(s.ReopenFailures) }},
+	{"tailf_dropped_lines_total", "counter", "Lines dropped by the rate limit.",
+		func(s tailf.Stats) float64 { return float64(s.Dropped) }},
+	{"tailf_blocked_seconds_total", "counter", "Time spent waiting for the file to grow.",
+		func(s tailf.Stats) float64 { return s.Blocked.Seconds() }},
+	{"tailf_lag_bytes", "gauge", "Bytes of the file not read yet.",
+		func(s tailf.Stats) float64 { return float64(s.Lag) }},
+	{"tailf_polling", "gauge", "Whether the file is polled instead of watched.",
+		func(s tailf.Stats) float64 {
+			if s.Polling {
+				return 1
+			}
+			return 0
+		}},
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.followers))
+	stats := make(map[string]tailf.Stats, len(m.followers))
+	for name, f := range m.followers {
+		names = append(names, name)
+		stats[name] = f.Stats()
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.typ)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{file=\"%s\"} %g\n", metric.name, labelEscaper.Replace(name), metric.value(stats[name]))
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
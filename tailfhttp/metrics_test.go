@@ -0,0 +1,45 @@
+package tailfhttp_test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aybabtme/tailf"
+	"github.com/aybabtme/tailf/tailfhttp"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	withServer(t, "one\ntwo\n", func(srv *httptest.Server, filename string, file *os.File) {
+		follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer follow.Close()
+		if _, err := follow.Next(); err != nil {
+			t.Fatal(err)
+		}
+
+		metrics := &tailfhttp.Metrics{}
+		metrics.Add(`app "main"`, follow)
+
+		rec := httptest.NewRecorder()
+		metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		body, _ := ioutil.ReadAll(rec.Body)
+
+		for _, want := range []string{
+			"# TYPE tailf_read_bytes_total counter\n",
+			`tailf_read_bytes_total{file="app \"main\""} 8` + "\n",
+			`tailf_read_lines_total{file="app \"main\""} 2` + "\n",
+			`tailf_lag_bytes{file="app \"main\""} 0` + "\n",
+			`tailf_dropped_lines_total{file="app \"main\""} 0` + "\n",
+			`tailf_polling{file="app \"main\""} 0` + "\n",
+		} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("want %q in:\n%s", want, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package tailfhttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aybabtme/tailf"
+	"golang.org/x/net/websocket"
+)
+
+// Message is what a WebSocket client receives, one per line.
+type Message struct {
+	// Offset is where the line begins in the file, which Dev and
+	// Ino identify, the file having maybe been rotated since.
+	Offset int64  `json:"offset"`
+	Dev    uint64 `json:"dev,omitempty"`
+	Ino    uint64 `json:"ino,omitempty"`
+	Line   string `json:"line,omitempty"`
+	// Error is set on the last message when the stream ends, or when
+	// a control message is rejected.
+	Error string `json:"error,omitempty"`
+}
+
+// Control is what a WebSocket client sends to steer its stream.
+type Control struct {
+	// Op is one of "pause", "resume", "filter" or "seek".
+	Op string `json:"op"`
+	// Grep and GrepV replace the filter of the stream, for "filter".
+	Grep  []string `json:"grep,omitempty"`
+	GrepV []string `json:"grepV,omitempty"`
+	// Lines is how many lines back from the end to restart the
+	// stream at, for "seek".
+	Lines int `json:"lines,omitempty"`
+}
+
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// serveWebSocket streams a file as JSON Messages and obeys the Control
+// messages of the client. Lines are only read from the follower when
+// they can be sent, so a paused or slow client holds back the follower
+// instead of piling up lines in memory.
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request, filename string) {
+	req, err := parseRequest(r, filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	checkOrigin := h.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	handshake := func(config *websocket.Config, r *http.Request) (err error) {
+		if !checkOrigin(r) {
+			return fmt.Errorf("origin %q not allowed", r.Header.Get("Origin"))
+		}
+		config.Origin, err = websocket.Origin(config, r)
+		return err
+	}
+
+	websocket.Server{Handshake: handshake, Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		quit := make(chan struct{})
+		defer close(quit)
+		ctrlc := make(chan Control)
+		go func() {
+			defer close(ctrlc)
+			for {
+				var ctrl Control
+				if err := websocket.JSON.Receive(ws, &ctrl); err != nil {
+					return
+				}
+				select {
+				case ctrlc <- ctrl:
+				case <-quit:
+					return
+				}
+			}
+		}()
+
+		s, err := startStream(filename, req.opts)
+		if err != nil {
+			_ = websocket.JSON.Send(ws, Message{Error: err.Error()})
+			return
+		}
+		defer func() { s.stop() }()
+
+		filter := req.filter
+		paused := false
+		for {
+			linec := s.linec
+			if paused {
+				linec = nil
+			}
+
+			select {
+			case rec, ok := <-linec:
+				if !ok {
+					_ = websocket.JSON.Send(ws, Message{Error: io.EOF.Error()})
+					return
+				}
+				if !filter.Match(rec.Data) {
+					continue
+				}
+				msg := Message{Offset: rec.FileOffset, Dev: rec.Dev, Ino: rec.Ino, Line: string(rec.Line())}
+				if err := websocket.JSON.Send(ws, msg); err != nil {
+					return
+				}
+
+			case ctrl, ok := <-ctrlc:
+				if !ok {
+					// the client is gone
+					return
+				}
+				switch ctrl.Op {
+				case "pause":
+					paused = true
+				case "resume":
+					paused = false
+				case "filter":
+					f, err := compileFilter(ctrl.Grep, ctrl.GrepV)
+					if err != nil {
+						_ = websocket.JSON.Send(ws, Message{Error: err.Error()})
+						continue
+					}
+					filter = f
+				case "seek":
+					start, err := tailf.LastLinesOffset(filename, ctrl.Lines)
+					if err != nil {
+						_ = websocket.JSON.Send(ws, Message{Error: err.Error()})
+						continue
+					}
+					next, err := startStream(filename, tailf.Options{FromStart: true, Offset: start})
+					if err != nil {
+						_ = websocket.JSON.Send(ws, Message{Error: err.Error()})
+						continue
+					}
+					s.stop()
+					s = next
+				default:
+					_ = websocket.JSON.Send(ws, Message{Error: "unknown op " + ctrl.Op})
+				}
+			}
+		}
+	}}.ServeHTTP(w, r)
+}
+
+// sameOrigin tells if the Origin of a request is the host it's for.
+func sameOrigin(r *http.Request) bool {
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	return err == nil && origin.Host != "" && strings.EqualFold(origin.Host, r.Host)
+}
+
+// stream reads the lines of a follower one at a time, as they're
+// taken from linec.
+type stream struct {
+	follow *tailf.Follower
+	linec  chan *tailf.Record
+	done   chan struct{}
+}
+
+func startStream(filename string, opts tailf.Options) (*stream, error) {
+	follow, err := tailf.FollowWithOptions(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	s := &stream{
+		follow: follow,
+		linec:  make(chan *tailf.Record),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.linec)
+		for {
+			rec, err := follow.Next()
+			if err != nil {
+				return
+			}
+			rec.Ack()
+			select {
+			case s.linec <- rec:
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+func (s *stream) stop() {
+	close(s.done)
+	_ = s.follow.Close()
+}
+
+func compileFilter(grep, grepV []string) (*tailf.Filter, error) {
+	filter := &tailf.Filter{}
+	for _, expr := range grep {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Include = append(filter.Include, re)
+	}
+	for _, expr := range grepV {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		filter.Exclude = append(filter.Exclude, re)
+	}
+	return filter, nil
+}
@@ -0,0 +1,89 @@
+package tailfhttp_test
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf/tailfhttp"
+	"golang.org/x/net/websocket"
+)
+
+func dial(t *testing.T, srv *httptest.Server, query url.Values) *websocket.Conn {
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?" + query.Encode()
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	if err != nil {
+		t.Fatalf("couldn't dial %q: %v", wsURL, err)
+	}
+	return ws
+}
+
+func receive(t *testing.T, ws *websocket.Conn) tailfhttp.Message {
+	var msg tailfhttp.Message
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatalf("couldn't receive: %v", err)
+	}
+	return msg
+}
+
+func send(t *testing.T, ws *websocket.Conn, ctrl tailfhttp.Control) {
+	if err := websocket.JSON.Send(ws, ctrl); err != nil {
+		t.Fatalf("couldn't send %v: %v", ctrl, err)
+	}
+}
+
+func TestWebSocketControl(t *testing.T) {
+	withServer(t, "one\ntwo\n", func(srv *httptest.Server, filename string, file *os.File) {
+		ws := dial(t, srv, url.Values{"path": {filename}, "n": {"1"}})
+		defer ws.Close()
+
+		if msg := receive(t, ws); msg.Line != "two" || msg.Offset != 4 {
+			t.Errorf("want two@4, got %+v", msg)
+		}
+
+		send(t, ws, tailfhttp.Control{Op: "pause"})
+		time.Sleep(50 * time.Millisecond)
+		if _, err := file.WriteString("three\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		var msg tailfhttp.Message
+		ws.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if err := websocket.JSON.Receive(ws, &msg); err == nil {
+			t.Errorf("received %+v while paused", msg)
+		}
+
+		send(t, ws, tailfhttp.Control{Op: "filter", GrepV: []string{"^four"}})
+		send(t, ws, tailfhttp.Control{Op: "resume"})
+		if _, err := file.WriteString("four\nfive\n"); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"three", "five"} {
+			if msg := receive(t, ws); msg.Line != want {
+				t.Errorf("want %q, got %+v", want, msg)
+			}
+		}
+
+		send(t, ws, tailfhttp.Control{Op: "seek", Lines: 3})
+		for _, want := range []string{"three", "five"} {
+			if msg := receive(t, ws); msg.Line != want {
+				t.Errorf("after seek, want %q, got %+v", want, msg)
+			}
+		}
+	})
+}
+
+func TestWebSocketRejectsForeignOrigin(t *testing.T) {
+	withServer(t, "one\n", func(srv *httptest.Server, filename string, file *os.File) {
+		wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?" + url.Values{"path": {filename}}.Encode()
+		ws, err := websocket.Dial(wsURL, "", "http://evil.example")
+		if err == nil {
+			ws.Close()
+			t.Error("want a connection from another site to be rejected")
+		}
+	})
+}
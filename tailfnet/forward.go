@@ -0,0 +1,264 @@
+/*
+Package tailfnet forwards the lines of a followed file to a TCP, UDP or
+Unix socket destination.
+
+Lines go through a bounded spool before being sent, so that a
+destination going away doesn't lose them: the Forwarder reconnects with
+backoff and resends what wasn't written. Lines are acknowledged once
+written to the destination, so a follower keeping a checkpoint only
+moves it past the lines that made it there.
+*/
+package tailfnet
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/aybabtme/tailf"
+)
+
+// Framing is how lines are delimited on the wire, as described by
+// RFC 6587.
+type Framing int
+
+const (
+	// NewlineFraming ends each line with a LF.
+	NewlineFraming Framing = iota
+	// OctetCounting prefixes each line with its length and a space.
+	OctetCounting
+)
+
+// Config of a Forwarder.
+type Config struct {
+	// Network and Address of the destination, as understood by
+	// net.Dial: "tcp", "udp", "unix" or "unixgram".
+	Network string
+	Address string
+
+	Framing Framing
+
+	// Spool holds the lines not sent yet. Defaults to a memory
+	// spool of 1024 lines.
+	Spool Spool
+
+	// MinBackoff and MaxBackoff bound the wait between two
+	// connection attempts. Default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Dial connects to the destination. Defaults to net.Dial.
+	Dial func(network, address string) (net.Conn, error)
+}
+
+// Forwarder sends the lines of a follower to a destination.
+type Forwarder struct {
+	cfg Config
+	src tailf.RecordReader
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	srcErr error
+	closed bool
+	closec chan struct{}
+	conn   net.Conn
+}
+
+// New returns a Forwarder of the records of src, usually a follower.
+func New(src tailf.RecordReader, cfg Config) *Forwarder {
+	if cfg.Spool == nil {
+		cfg.Spool = NewMemorySpool(1024)
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Dial == nil {
+		cfg.Dial = net.Dial
+	}
+	f := &Forwarder{cfg: cfg, src: src, closec: make(chan struct{})}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Run forwards the lines until the source ends and the spool is
+// drained, or until the Forwarder is closed.
+func (f *Forwarder) Run() error {
+	go f.fill()
+	defer f.setConn(nil)
+
+	var conn net.Conn
+	backoff := f.cfg.MinBackoff
+	for {
+		rec, err := f.next()
+		if rec == nil {
+			return err
+		}
+
+		if conn == nil {
+			conn, err = f.cfg.Dial(f.cfg.Network, f.cfg.Address)
+			if err != nil {
+				if !f.sleep(backoff) {
+					return nil
+				}
+				backoff = imin64(2*backoff, f.cfg.MaxBackoff)
+				continue
+			}
+			if !f.setConn(conn) {
+				return nil
+			}
+		}
+
+		if _, err := conn.Write(frame(f.cfg.Framing, rec)); err != nil {
+			// resend this record on a new connection, once the
+			// destination had time to recover
+			f.setConn(nil)
+			conn = nil
+			if !f.sleep(backoff) {
+				return nil
+			}
+			backoff = imin64(2*backoff, f.cfg.MaxBackoff)
+			continue
+		}
+		backoff = f.cfg.MinBackoff
+
+		if err := f.pop(); err != nil {
+			return err
+		}
+		if err := rec.Ack(); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops forwarding, closing the connection to the destination to
+// interrupt a write in progress. The lines left in the spool aren't
+// sent. The source isn't closed: until it is, like with the Close of a
+// follower, a line is still being waited for from it.
+func (f *Forwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		close(f.closec)
+		f.cond.Broadcast()
+	}
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn = nil
+	}
+	return nil
+}
+
+// setConn replaces the connection to the destination, closing the
+// previous one. It closes conn and returns false if the Forwarder is
+// closed.
+func (f *Forwarder) setConn(conn net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn != nil {
+		_ = f.conn.Close()
+	}
+	f.conn = conn
+	if f.closed && conn != nil {
+		_ = conn.Close()
+		f.conn = nil
+		return false
+	}
+	return true
+}
+
+// fill moves the records of the source to the spool, waiting for room
+// when it's full.
+func (f *Forwarder) fill() {
+	for {
+		rec, err := f.src.Next()
+
+		f.mu.Lock()
+		if err != nil {
+			f.srcErr = err
+			f.cond.Broadcast()
+			f.mu.Unlock()
+			return
+		}
+		for !f.closed {
+			ok, err := f.cfg.Spool.Push(rec)
+			if err != nil {
+				f.srcErr = err
+				break
+			}
+			if ok {
+				break
+			}
+			f.cond.Wait()
+		}
+		done := f.closed || f.srcErr != nil
+		f.cond.Broadcast()
+		f.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// next waits for a record to send. It returns a nil record once there's
+// nothing more to send.
+func (f *Forwarder) next() (*tailf.Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for !f.closed {
+		rec, err := f.cfg.Spool.Peek()
+		if err != nil || rec != nil {
+			return rec, err
+		}
+		if f.srcErr != nil {
+			if f.srcErr == io.EOF {
+				return nil, nil
+			}
+			return nil, f.srcErr
+		}
+		f.cond.Wait()
+	}
+	return nil, nil
+}
+
+func (f *Forwarder) pop() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.cfg.Spool.Pop()
+	f.cond.Broadcast()
+	return err
+}
+
+// sleep waits for d, or returns false if the Forwarder is closed.
+func (f *Forwarder) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-f.closec:
+		return false
+	}
+}
+
+func frame(framing Framing, rec *tailf.Record) []byte {
+	line := rec.Line()
+	switch framing {
+	case OctetCounting:
+		return append([]byte(fmt.Sprintf("%d ", len(line))), line...)
+	default:
+		return append(append(make([]byte, 0, len(line)+1), line...), '\n')
+	}
+}
+
+func imin64(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,214 @@
+package tailfnet_test
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aybabtme/tailf"
+	"github.com/aybabtme/tailf/tailfnet"
+)
+
+func TestForwardOctetCounting(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailfnet_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	if err := ioutil.WriteFile(filename, []byte("hello\nworld\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store := tailf.FileCheckpointStore(filepath.Join(dir, "checkpoint"))
+	follow, err := tailf.FollowWithOptions(filename, tailf.Options{FromStart: true, Checkpoint: store, CheckpointInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	fwd := tailfnet.New(follow, tailfnet.Config{
+		Network: "tcp",
+		Address: ln.Addr().String(),
+		Framing: tailfnet.OctetCounting,
+	})
+	go fwd.Run()
+	defer fwd.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	want := "5 hello5 world"
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	follow.Close()
+	for i := 0; i < 100; i++ {
+		cp, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cp != nil && cp.Offset == 12 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("the checkpoint didn't move past the lines forwarded")
+}
+
+func TestForwardReconnects(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailfnet_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "sink.sock")
+
+	spool, err := tailfnet.NewFileSpool(filepath.Join(dir, "spool"), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	src, w := io.Pipe()
+	fwd := tailfnet.New(tailf.NewLineReader(src), tailfnet.Config{
+		Network:    "unix",
+		Address:    sock,
+		Spool:      spool,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	})
+	done := make(chan error)
+	go func() { done <- fwd.Run() }()
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		line := strings.Repeat("x", i)
+		want = append(want, line)
+	}
+
+	// write everything while the destination is down
+	go func() {
+		for _, line := range want {
+			io.WriteString(w, line+"\n")
+		}
+		w.Close()
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scan := bufio.NewScanner(conn)
+				for scan.Scan() {
+					mu.Lock()
+					got = append(got, scan.Text())
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("forwarding took too long")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestForwardCloseInterruptsWrite(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	fwd := tailfnet.New(tailf.NewLineReader(strings.NewReader("hello\n")), tailfnet.Config{
+		Dial: func(network, address string) (net.Conn, error) { return client, nil },
+	})
+	done := make(chan error)
+	go func() { done <- fwd.Run() }()
+
+	// nothing reads the other end, the write blocks
+	time.Sleep(20 * time.Millisecond)
+	fwd.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't interrupt the write")
+	}
+}
+
+func TestForwardBacksOffAfterWriteErrors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		dials int
+	)
+	fwd := tailfnet.New(tailf.NewLineReader(strings.NewReader("hello\n")), tailfnet.Config{
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+		// the destination accepts the connection, and closes it
+		Dial: func(network, address string) (net.Conn, error) {
+			mu.Lock()
+			dials++
+			mu.Unlock()
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		},
+	})
+	go fwd.Run()
+	time.Sleep(200 * time.Millisecond)
+	fwd.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dials > 20 {
+		t.Errorf("want a backoff between the connections, dialed %d times in 200ms", dials)
+	}
+}
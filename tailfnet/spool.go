@@ -0,0 +1,184 @@
+package tailfnet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aybabtme/tailf"
+)
+
+// Spool is a bounded queue of the records waiting to be sent. The
+// Forwarder serializes its calls.
+type Spool interface {
+	// Push appends a record, unless the spool is full.
+	Push(rec *tailf.Record) (ok bool, err error)
+	// Peek returns the oldest record, or nil if the spool is empty.
+	Peek() (*tailf.Record, error)
+	// Pop removes the oldest record.
+	Pop() error
+}
+
+// MemorySpool is a Spool holding a fixed number of records in memory.
+type MemorySpool struct {
+	size  int
+	queue []*tailf.Record
+}
+
+// NewMemorySpool returns a spool of up to size records.
+func NewMemorySpool(size int) *MemorySpool {
+	if size < 1 {
+		size = 1
+	}
+	return &MemorySpool{size: size}
+}
+
+func (m *MemorySpool) Push(rec *tailf.Record) (bool, error) {
+	if len(m.queue) >= m.size {
+		return false, nil
+	}
+	m.queue = append(m.queue, rec)
+	return true, nil
+}
+
+func (m *MemorySpool) Peek() (*tailf.Record, error) {
+	if len(m.queue) == 0 {
+		return nil, nil
+	}
+	return m.queue[0], nil
+}
+
+func (m *MemorySpool) Pop() error {
+	if len(m.queue) != 0 {
+		m.queue[0] = nil
+		m.queue = m.queue[1:]
+	}
+	return nil
+}
+
+// entryHeader is the length of a spooled record.
+const entryHeader = 4
+
+// FileSpool is a Spool keeping records in a file, up to a number of
+// bytes. It lets a long outage be absorbed without holding the lines in
+// memory: only a stub of each record is, without its data, which keeps
+// its acknowledgement handle. Its content isn't meant to survive a
+// restart: the checkpoint of what was forwarded is what does.
+type FileSpool struct {
+	file     *os.File
+	maxBytes int64
+	rpos     int64
+	wpos     int64
+	peeked   *tailf.Record
+	peekSize int64
+
+	// stubs are the spooled records without their data, which keep
+	// their acknowledgement handle.
+	stubs []*tailf.Record
+}
+
+// NewFileSpool creates a spool in filename, up to maxBytes large.
+func NewFileSpool(filename string, maxBytes int64) (*FileSpool, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSpool{file: file, maxBytes: maxBytes}, nil
+}
+
+func (s *FileSpool) Push(rec *tailf.Record) (bool, error) {
+	size := int64(entryHeader + len(rec.Data))
+	if s.wpos-s.rpos+size > s.maxBytes && s.wpos != s.rpos {
+		return false, nil
+	}
+	if s.wpos+size > s.maxBytes && s.rpos != 0 {
+		if err := s.compact(); err != nil {
+			return false, err
+		}
+	}
+
+	entry := make([]byte, size)
+	binary.BigEndian.PutUint32(entry, uint32(len(rec.Data)))
+	copy(entry[entryHeader:], rec.Data)
+	if _, err := s.file.WriteAt(entry, s.wpos); err != nil {
+		return false, err
+	}
+	s.wpos += size
+
+	stub := *rec
+	stub.Data = nil
+	s.stubs = append(s.stubs, &stub)
+	return true, nil
+}
+
+func (s *FileSpool) Peek() (*tailf.Record, error) {
+	if s.peeked != nil || s.rpos == s.wpos {
+		return s.peeked, nil
+	}
+	var header [entryHeader]byte
+	if _, err := s.file.ReadAt(header[:], s.rpos); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if s.rpos+entryHeader+int64(n) > s.wpos || len(s.stubs) == 0 {
+		return nil, fmt.Errorf("spool %s is corrupted at %d", s.file.Name(), s.rpos)
+	}
+	rec := s.stubs[0]
+	rec.Data = make([]byte, n)
+	if _, err := s.file.ReadAt(rec.Data, s.rpos+entryHeader); err != nil && err != io.EOF {
+		return nil, err
+	}
+	s.peeked, s.peekSize = rec, entryHeader+int64(n)
+	return rec, nil
+}
+
+func (s *FileSpool) Pop() error {
+	if _, err := s.Peek(); err != nil || s.peeked == nil {
+		return err
+	}
+	s.rpos += s.peekSize
+	s.peeked = nil
+	s.stubs[0] = nil
+	s.stubs = s.stubs[1:]
+	if s.rpos == s.wpos {
+		// empty, start over at the beginning of the file
+		s.rpos, s.wpos = 0, 0
+		return s.file.Truncate(0)
+	}
+	return nil
+}
+
+// compactChunk is how much of the spool compact moves at a time.
+const compactChunk = 64 << 10
+
+// compact moves the records left to the start of the file, a chunk at a
+// time.
+func (s *FileSpool) compact() error {
+	size := s.wpos - s.rpos
+	buf := make([]byte, compactChunk)
+	for off := int64(0); off < size; off += compactChunk {
+		chunk := buf
+		if size-off < compactChunk {
+			chunk = buf[:size-off]
+		}
+		if _, err := s.file.ReadAt(chunk, s.rpos+off); err != nil {
+			return err
+		}
+		// what's written is before what's left to read
+		if _, err := s.file.WriteAt(chunk, off); err != nil {
+			return err
+		}
+	}
+	s.rpos, s.wpos = 0, size
+	return s.file.Truncate(s.wpos)
+}
+
+// Close removes the spool's file.
+func (s *FileSpool) Close() error {
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
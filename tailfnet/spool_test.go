@@ -0,0 +1,68 @@
+package tailfnet_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aybabtme/tailf"
+	"github.com/aybabtme/tailf/tailfnet"
+)
+
+func TestFileSpoolCompacts(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "tailfnet_test_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	spool, err := tailfnet.NewFileSpool(filepath.Join(dir, "spool"), 300<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	record := func(i int) []byte {
+		return bytes.Repeat([]byte(fmt.Sprintf("%04d", i)), 250)
+	}
+	pushed, popped := 0, 0
+	push := func() bool {
+		ok, err := spool.Push(&tailf.Record{Data: record(pushed)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			pushed++
+		}
+		return ok
+	}
+	pop := func() {
+		rec, err := spool.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rec.Data, record(popped)) {
+			t.Fatalf("want record %d, got %.8q...", popped, rec.Data)
+		}
+		if err := spool.Pop(); err != nil {
+			t.Fatal(err)
+		}
+		popped++
+	}
+
+	// fill it, then make room at its start, so that the next records
+	// move what's left, over several chunks, to the start of the file
+	for push() {
+	}
+	for i := 0; i < 100; i++ {
+		pop()
+	}
+	for push() {
+	}
+	for popped < pushed {
+		pop()
+	}
+}